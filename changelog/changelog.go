@@ -0,0 +1,242 @@
+package changelog
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	version "github.com/hashicorp/go-version"
+)
+
+// Unreleased is the name of the section with the changes that are not released yet
+const Unreleased = "Unreleased"
+
+// DefaultPreamble is the preamble of new changelogs
+const DefaultPreamble = `# Changelog
+All notable changes to this project will be documented in this file.
+
+The format is based on [Keep a Changelog](https://keepachangelog.com/en/1.0.0/),
+and this project adheres to [Semantic Versioning](https://semver.org/spec/v2.0.0.html).`
+
+var (
+	headingPattern = regexp.MustCompile(`^##\s+\[?([^\]\s]+)\]?(?:\s+-)?\s*(.*?)\s*$`)
+	linkPattern    = regexp.MustCompile(`^\[([^\]]+)\]:\s*(\S+)\s*$`)
+	fencePattern   = regexp.MustCompile("^\\s*(```|~~~)")
+)
+
+// Changelog is a Keep a Changelog style file, with a preamble, a section per
+// release and a footer with the compare links of the sections
+type Changelog struct {
+	Preamble string
+	Sections []Section
+	Links    []Link
+}
+
+// Section is the section of a release in the changelog. Parsed sections are
+// written back as they were, unless they are replaced
+type Section struct {
+	Name    string
+	Date    string
+	Body    string
+	heading string
+	raw     string
+}
+
+// Link is a link reference definition in the footer of the changelog
+type Link struct {
+	Name string
+	URL  string
+}
+
+// Parse parses a Keep a Changelog style file. Everything before the first
+// second level heading is the preamble, and link reference definitions at the
+// end of the file are the footer
+func Parse(s string) *Changelog {
+	c := &Changelog{}
+	var preamble []string
+	var section *Section
+	var body []string
+	inFence := false
+	flush := func() {
+		if section != nil {
+			section.Body = strings.TrimSpace(strings.Join(body, "\n"))
+			section.raw = strings.TrimRight(strings.Join(append([]string{section.heading}, body...), "\n"), "\n ")
+			c.Sections = append(c.Sections, *section)
+		}
+	}
+
+	lines := strings.Split(strings.Replace(s, "\r\n", "\n", -1), "\n")
+	lines = c.parseLinks(lines)
+	for _, line := range lines {
+		if fencePattern.MatchString(line) {
+			inFence = !inFence
+		}
+		if match := headingPattern.FindStringSubmatch(line); match != nil && !inFence {
+			flush()
+			section = &Section{Name: match[1], Date: match[2], heading: line}
+			body = nil
+		} else if section == nil {
+			preamble = append(preamble, line)
+		} else {
+			body = append(body, line)
+		}
+	}
+	flush()
+	c.Preamble = strings.TrimSpace(strings.Join(preamble, "\n"))
+	return c
+}
+
+// parseLinks parses the link reference definitions at the end of the lines, and
+// returns the lines before them
+func (c *Changelog) parseLinks(lines []string) []string {
+	end := len(lines)
+	for end > 0 {
+		line := strings.TrimSpace(lines[end-1])
+		if match := linkPattern.FindStringSubmatch(line); match != nil {
+			c.Links = append([]Link{{Name: match[1], URL: match[2]}}, c.Links...)
+		} else if line != "" {
+			break
+		}
+		end--
+	}
+	return lines[:end]
+}
+
+// String returns the markdown of the changelog
+func (c *Changelog) String() string {
+	var b strings.Builder
+	preamble := c.Preamble
+	if preamble == "" {
+		preamble = DefaultPreamble
+	}
+	b.WriteString(preamble + "\n\n")
+	for _, section := range c.Sections {
+		if section.raw != "" {
+			b.WriteString(section.raw + "\n\n")
+			continue
+		}
+		b.WriteString(section.Heading() + "\n\n")
+		if section.Body != "" {
+			b.WriteString(section.Body + "\n\n")
+		}
+	}
+	for _, link := range c.Links {
+		fmt.Fprintf(&b, "[%s]: %s\n", link.Name, link.URL)
+	}
+	return strings.TrimRight(b.String(), "\n") + "\n"
+}
+
+// Heading returns the heading line of the section
+func (s *Section) Heading() string {
+	if s.heading != "" {
+		return s.heading
+	}
+	if s.Date != "" {
+		return fmt.Sprintf("## [%s] - %s", s.Name, s.Date)
+	}
+	return fmt.Sprintf("## [%s]", s.Name)
+}
+
+// IsUnreleased checks if the section contains the unreleased changes
+func (s *Section) IsUnreleased() bool {
+	return strings.EqualFold(s.Name, Unreleased)
+}
+
+// Version returns the version of the section, or nil if its name isn't one
+func (s *Section) Version() *version.Version {
+	v, err := version.NewVersion(s.Name)
+	if err != nil {
+		return nil
+	}
+	return v
+}
+
+// Is checks if two sections are for the same release. Versions are equal
+// regardless of their prefixes, so 'v1.2.3' is the same release as '1.2.3'
+func (s *Section) Is(other Section) bool {
+	if strings.EqualFold(s.Name, other.Name) {
+		return true
+	}
+	v, otherVersion := s.Version(), other.Version()
+	return v != nil && otherVersion != nil && v.Equal(otherVersion)
+}
+
+// Section returns the section with the name, if any
+func (c *Changelog) Section(name string) (Section, bool) {
+	for _, section := range c.Sections {
+		if section.Is(Section{Name: name}) {
+			return section, true
+		}
+	}
+	return Section{}, false
+}
+
+// Set replaces the section of the same release, or inserts it. The unreleased
+// section is inserted first, and versions before the first lower version
+func (c *Changelog) Set(section Section) {
+	section.heading, section.raw = "", ""
+	for i := range c.Sections {
+		if c.Sections[i].Is(section) {
+			c.Sections[i] = section
+			return
+		}
+	}
+	index := len(c.Sections)
+	v := section.Version()
+	for i, existing := range c.Sections {
+		if section.IsUnreleased() {
+			index = i
+			break
+		}
+		if existingVersion := existing.Version(); v != nil && existingVersion != nil && existingVersion.LessThan(v) {
+			index = i
+			break
+		}
+	}
+	c.Sections = append(c.Sections, Section{})
+	copy(c.Sections[index+1:], c.Sections[index:])
+	c.Sections[index] = section
+}
+
+// Latest returns the section with the highest version, or nil if there is none
+func (c *Changelog) Latest() *Section {
+	var latest *Section
+	for i := range c.Sections {
+		if v := c.Sections[i].Version(); v != nil && (latest == nil || v.GreaterThan(latest.Version())) {
+			latest = &c.Sections[i]
+		}
+	}
+	return latest
+}
+
+// SetLink sets the URL of a link in the footer. Links are ordered like the
+// sections they belong to, followed by the other links
+func (c *Changelog) SetLink(name, url string) {
+	found := false
+	for i := range c.Links {
+		if strings.EqualFold(c.Links[i].Name, name) {
+			c.Links[i].URL = url
+			found = true
+		}
+	}
+	if !found {
+		c.Links = append(c.Links, Link{Name: name, URL: url})
+	}
+
+	var sorted []Link
+	used := make([]bool, len(c.Links))
+	for _, section := range c.Sections {
+		for i, link := range c.Links {
+			if !used[i] && section.Is(Section{Name: link.Name}) {
+				sorted = append(sorted, link)
+				used[i] = true
+			}
+		}
+	}
+	for i, link := range c.Links {
+		if !used[i] {
+			sorted = append(sorted, link)
+		}
+	}
+	c.Links = sorted
+}
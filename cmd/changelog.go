@@ -16,126 +16,474 @@ package cmd
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path"
+	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/franzwilhelm/gitflow-release-notes/diff"
+	"github.com/franzwilhelm/gitflow-release-notes/githubutil"
 	"github.com/franzwilhelm/gitflow-release-notes/release"
 	"github.com/franzwilhelm/gitflow-release-notes/slack"
-	version "github.com/hashicorp/go-version"
+	"github.com/google/go-github/github"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 var (
 	overwrite       bool
+	recreate        bool
+	draft           bool
+	diffGithub      bool
+	attachFormats   []string
+	assets          []string
 	pushToGithub    bool
 	saveMarkdown    bool
 	slackChannel    string
 	slackWebhookURL string
 	slackIconURL    string
+	templateFile    string
+	outputFormat    string
+	updateFile      string
+	commitFile      bool
+	commitBranch    string
+	commitMessage   string
+	commitAuthor    string
+	commitEmail     string
+	commitPR        bool
+	since           string
+	until           string
+	timezone        string
+	baseBranch      string
+	timeLayouts     = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02"}
 )
 
-func parseTagInput(input string) (baseVersion, headVersion *version.Version, versionPrefix string, err error) {
-	tags := strings.Split(input, "..")
-	baseVersion, err = version.NewVersion(tags[0])
-	if err != nil {
-		return nil, nil, "", err
-	}
-	switch len(tags) {
+func parseRefInput(input string) (base, head string, err error) {
+	refs := strings.Split(input, "..")
+	switch len(refs) {
 	case 1:
-		headVersion = baseVersion
+		base, head = refs[0], refs[0]
 	case 2:
-		headVersion, err = version.NewVersion(tags[1])
+		base, head = refs[0], refs[1]
+	default:
+		return "", "", errors.New("input argument should only contain one double dot (..)")
+	}
+	if base == "" || head == "" {
+		return "", "", errors.New("input argument should contain a ref on both sides of the double dot (..)")
+	}
+	return base, head, nil
+}
+
+// parseTime parses a timestamp in one of the time layouts. Timestamps without a
+// time zone are parsed in the provided location. If endOfDay is true, a date
+// without a time is moved to the end of the day, so it's included in windows
+func parseTime(input string, loc *time.Location, endOfDay bool) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		t, err := time.ParseInLocation(layout, input, loc)
+		if err != nil {
+			continue
+		}
+		if endOfDay && layout == "2006-01-02" {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("could not parse time %s. Use a date like 2006-01-02 or a timestamp like 2006-01-02T15:04", input)
+}
+
+// parseTimeWindow parses the --since, --until and --timezone flags
+func parseTimeWindow() (window release.TimeWindow, err error) {
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return window, err
+	}
+	if window.Since, err = parseTime(since, loc, false); err != nil {
+		return window, err
+	}
+	window.Until = time.Now().In(loc)
+	if until != "" {
+		if window.Until, err = parseTime(until, loc, true); err != nil {
+			return window, err
+		}
+	}
+	if !window.Since.Before(window.Until) {
+		return window, errors.New("--since must be before --until")
+	}
+	return window, nil
+}
+
+func initSlack(cmd *cobra.Command, args []string) error {
+	if slackChannel != "" && slackWebhookURL == "" {
+		return errors.New("--slack-webhook is needed to post to slack")
+	} else if slackChannel != "" {
+		slack.Initialize(slackWebhookURL)
+	}
+	return nil
+}
+
+// initCommit validates the flags for committing the changelog through Github
+func initCommit() error {
+	if (commitFile || commitPR) && updateFile == "" {
+		return errors.New("--commit and --commit-pr can only be used with --update-file")
+	} else if commitPR && !commitFile {
+		return errors.New("--commit-pr can only be used with --commit")
+	} else if (commitAuthor == "") != (commitEmail == "") {
+		return errors.New("--commit-author and --commit-email must be used together")
+	}
+	if commitFile {
+		_, err := repositoryPath(updateFile)
+		return err
+	}
+	return nil
+}
+
+// repositoryPath returns the path of a file in the repository, as used by the
+// Github contents API. For instance './docs/CHANGELOG.md' returns 'docs/CHANGELOG.md'
+func repositoryPath(file string) (string, error) {
+	p := path.Clean(filepath.ToSlash(file))
+	if path.IsAbs(p) || filepath.IsAbs(file) || p == ".." || strings.HasPrefix(p, "../") {
+		return "", fmt.Errorf("%s must be a path relative to the root of the repository", file)
+	}
+	return p, nil
+}
+
+// initOutput validates the output formats, initializes slack and the ledger, and
+// loads the markdown template, which can be set with --template or in the config file
+func initOutput(cmd *cobra.Command, args []string) error {
+	if err := initSlack(cmd, args); err != nil {
+		return err
+	}
+	if err := initLedger(); err != nil {
+		return err
+	}
+	if err := initCommit(); err != nil {
+		return err
+	}
+	for _, format := range append([]string{outputFormat}, attachFormats...) {
+		if _, err := release.FormatExtension(format); err != nil {
+			return err
+		}
+	}
+	if templateFile == "" {
+		templateFile = viper.GetString("template")
+	}
+	if templateFile != "" {
+		text, err := ioutil.ReadFile(templateFile)
 		if err != nil {
-			return nil, nil, "", err
+			return fmt.Errorf("could not read template: %v", err)
 		}
+		return release.UseTemplate(string(text))
+	}
+	return nil
+}
+
+// outputReleases pushes the releases to Github and Slack, saves them to files,
+// adds them to a changelog file, or prints them, depending on the flags used
+func outputReleases(releases []release.Release) {
+	if diffGithub {
+		diffReleases(releases)
+		return
+	}
+	loadLedger()
+	if updateFile != "" && commitFile {
+		commitChangelogFile(releases)
+	} else if updateFile != "" {
+		if err := release.UpdateChangelogFile(updateFile, releases); err != nil {
+			logrus.WithError(err).Errorf("Could not update %s", updateFile)
+		} else if !dryRun {
+			logrus.Infof("Updated %s", updateFile)
+		}
+	}
+	pushToSlack := slackWebhookURL != "" && slackChannel != ""
+	ext, _ := release.FormatExtension(outputFormat)
+	for _, release := range releases {
+		log := logrus.WithField("release", release.Title())
+		if pushToGithub {
+			if _, err := release.PushToGithub(pushOptions()); err != nil {
+				log.WithError(err).Error("Could not push release to Github")
+			}
+		}
+		if pushToSlack {
+			log.Info("Pusing release to slack")
+			if err := release.PushToSlack(slackChannel, slackIconURL); err != nil {
+				log.WithError(err).Error("Could not push release to slack")
+			}
+		}
+		if saveMarkdown {
+			filename := release.Filename(ext)
+			if f, err := os.Create(filename); err != nil {
+				log.WithError(err).Error("Could not create file for changelog")
+			} else {
+				defer f.Close()
+				if err := release.Generate(f, outputFormat); err != nil {
+					log.WithError(err).Errorf("Could not generate %s changelog", outputFormat)
+				} else {
+					log.Infof("Wrote changelog to %s", filename)
+				}
+			}
+		} else if !pushToGithub && !pushToSlack && updateFile == "" {
+			if err := printRelease(release); err != nil {
+				log.WithError(err).Errorf("Could not generate %s changelog", outputFormat)
+			}
+		}
+	}
+}
+
+// printRelease prints the release in the output format. JSON is printed on a
+// single line, and YAML as a document starting with ---, so the output of
+// several releases is a JSON Lines or YAML stream that render can read back
+func printRelease(r release.Release) error {
+	buf := new(bytes.Buffer)
+	if err := r.Generate(buf, outputFormat); err != nil {
+		return err
+	}
+	switch outputFormat {
+	case release.JSON, release.SlackJSON:
+		compact := new(bytes.Buffer)
+		if err := json.Compact(compact, buf.Bytes()); err != nil {
+			return err
+		}
+		fmt.Println(compact.String())
+	case release.YAML:
+		fmt.Print("---\n" + buf.String())
 	default:
-		return nil, nil, "", errors.New("input argument should only contain one double dot (..)")
+		fmt.Println(buf.String())
+	}
+	return nil
+}
+
+// diffReleases prints the diff of the release notes in Github and the generated
+// ones, and exits with 2 if any of them differ
+func diffReleases(releases []release.Release) {
+	changed := false
+	for _, release := range releases {
+		log := logrus.WithField("release", release.Title())
+		d, err := release.DiffWithGithub()
+		if err != nil {
+			log.WithError(err).Fatal("Could not compare release with Github")
+		} else if d == "" {
+			log.Info("Release notes in Github are up to date")
+		} else {
+			fmt.Print(d)
+			changed = true
+		}
+	}
+	if changed {
+		os.Exit(exitChanged)
 	}
+}
 
-	return baseVersion, headVersion, strings.Replace(tags[0], baseVersion.String(), "", 1), nil
+// commitChangelogFile updates the changelog file in the repository through the
+// Github API, so no local clone is needed. The file is committed directly to
+// the commit branch, or to a changelog branch with a pull request against it
+func commitChangelogFile(releases []release.Release) {
+	if len(releases) == 0 {
+		return
+	}
+	path, err := repositoryPath(updateFile)
+	if err != nil {
+		logrus.WithError(err).Fatal("Could not commit changelog")
+	}
+	latest := releases[len(releases)-1]
+	branch := commitBranch
+	if commitPR {
+		branch = "changelog/" + latest.Slug()
+	}
+	log := logrus.WithFields(logrus.Fields{
+		"file":   path,
+		"branch": branch,
+	})
+
+	// The changelog is read from the commit branch in dry runs, if the branch
+	// of the pull request would be created
+	readBranch := branch
+	if commitPR {
+		exists, err := githubutil.BranchExists(branch)
+		if err != nil {
+			log.WithError(err).Fatal("Could not look up branch")
+		} else if !exists && dryRun {
+			release.DryRunf("would create branch %s from %s", branch, commitBranch)
+			readBranch = commitBranch
+		} else if !exists {
+			if err := githubutil.CreateBranch(branch, commitBranch); err != nil {
+				log.WithError(err).Fatal("Could not create branch")
+			}
+			log.Info("Created branch")
+		}
+	}
+
+	content, sha, err := githubutil.GetFileContent(path, readBranch)
+	if err != nil {
+		log.WithError(err).Fatal("Could not fetch changelog")
+	}
+	updated, err := release.UpdateChangelogContent(content, releases)
+	if err != nil {
+		log.WithError(err).Fatal("Could not update changelog")
+	}
+	message := commitMessage
+	if message == "" {
+		message = fmt.Sprintf("Update %s for %s", path, latest.Title())
+	}
+	if updated == content {
+		log.Info("Changelog is already up to date")
+	} else if dryRun {
+		release.DryRunf("would commit %s to %s:\n%s", path, branch, diff.Unified(content, updated, path, path))
+	} else {
+		var author *github.CommitAuthor
+		if commitAuthor != "" || commitEmail != "" {
+			author = &github.CommitAuthor{Name: &commitAuthor, Email: &commitEmail}
+		}
+		commit, err := githubutil.CommitFile(path, branch, message, updated, sha, author)
+		if err != nil {
+			log.WithError(err).Fatal("Could not commit changelog")
+		}
+		log.Infof("Committed changelog in %s", commit)
+	}
+
+	if !commitPR {
+		return
+	}
+	pr, err := githubutil.FindOpenPullRequest(branch, commitBranch)
+	if err != nil {
+		log.WithError(err).Fatal("Could not look up pull requests")
+	} else if pr != nil {
+		log.Infof("Pull request #%v is already open", pr.GetNumber())
+	} else if dryRun {
+		release.DryRunf("would open pull request from %s into %s", branch, commitBranch)
+	} else if pr, err = githubutil.CreatePullRequest(branch, commitBranch, message, ""); err != nil {
+		log.WithError(err).Fatal("Could not open pull request")
+	} else {
+		log.Infof("Opened pull request #%v", pr.GetNumber())
+	}
 }
 
 // changelogCmd represents the changelog command
 var changelogCmd = &cobra.Command{
-	Use:   "changelog [base-tag..head-tag]",
-	Short: "Generates changelogs for the specified tag or tag range",
-	Args:  cobra.MinimumNArgs(1),
-	PreRunE: func(cmd *cobra.Command, args []string) error {
-		if slackChannel != "" && slackWebhookURL == "" {
-			return errors.New("--slack-webhook is needed to post to slack")
-		} else if slackChannel != "" {
-			slack.Initialize(slackWebhookURL)
-		}
-		return nil
+	Use:   "changelog [base-ref..head-ref]",
+	Short: "Generates changelogs for the specified tag, ref or ref range",
+	Long: `Generates changelogs for the specified tag, ref or ref range.
+
+Both sides of the range can be a tag, branch, SHA or HEAD. Changes after
+the last tag of a range with a non-tag head are added as an unreleased
+release, which lets you preview the notes of a release branch before it is
+tagged. A single non-tag ref generates the unreleased changes since the
+newest tag reachable from it.
+
+Instead of a range, --since and --until generate a single changelog of the
+pull requests merged into the base branch in a time window. Dates without a
+time include the whole day.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if since != "" {
+			return cobra.NoArgs(cmd, args)
+		} else if until != "" {
+			return errors.New("--until can only be used with --since")
+		}
+		return cobra.MinimumNArgs(1)(cmd, args)
 	},
+	PreRunE: initOutput,
 	Run: func(cmd *cobra.Command, args []string) {
-		baseVersion, headVersion, tagPrefix, err := parseTagInput(args[0])
+		if since != "" {
+			window, err := parseTimeWindow()
+			if err != nil {
+				logrus.WithError(err).Fatal("Could not parse time window")
+			}
+			logrus.WithFields(logrus.Fields{
+				"repo":  repo.Name,
+				"owner": repo.Owner,
+			}).Infof("Generating changelog for pull requests merged into %s %s", baseBranch, window.String())
+			r, err := release.GenerateReleaseBetweenTimes(baseBranch, window)
+			if err != nil {
+				logrus.WithError(err).Fatal("Could not generate release")
+			}
+			outputReleases([]release.Release{*r})
+			return
+		}
+
+		base, head, err := parseRefInput(args[0])
 		if err != nil {
-			logrus.WithError(err).Fatal("Could not parse tag input")
+			logrus.WithError(err).Fatal("Could not parse ref input")
 		}
 
 		repoOwnerLog := logrus.WithFields(logrus.Fields{
 			"repo":  repo.Name,
 			"owner": repo.Owner,
 		})
-		if baseVersion.Equal(headVersion) {
-			repoOwnerLog.Infof("Generating changelog for %s", tagPrefix+baseVersion.String())
+		if base == head {
+			repoOwnerLog.Infof("Generating changelog for %s", head)
 		} else {
-			repoOwnerLog.Infof("Generating changelog for tags between %s and %s", tagPrefix+baseVersion.String(), tagPrefix+headVersion.String())
+			repoOwnerLog.Infof("Generating changelog for refs between %s and %s", base, head)
 		}
 
-		releases, err := release.GenerateReleasesBetweenTags(baseVersion, headVersion, tagPrefix)
+		releases, err := release.GenerateReleasesBetweenRefs(base, head)
 		if err != nil {
 			logrus.WithError(err).Fatalf("Could not generate releases")
 		}
 
-		pushToSlack := slackWebhookURL != "" && slackChannel != ""
-		for _, release := range releases {
-			log := logrus.WithField("release", release.TagName())
-			if pushToGithub {
-				if err := release.PushToGithub(overwrite); err != nil {
-					log.WithError(err).Error("Could not push release to Github")
-				}
-			}
-			if pushToSlack {
-				log.Info("Pusing release to slack")
-				if err := release.PushToSlack(slackChannel, slackIconURL); err != nil {
-					log.WithError(err).Error("Could not push release to slack")
-				}
-			}
-			if saveMarkdown {
-				filename := release.Filename("md")
-				if f, err := os.Create(filename); err != nil {
-					log.WithError(err).Error("Could not create file for changelog")
-				} else {
-					defer f.Close()
-					if err := release.GenerateMarkdownChangelog(f); err != nil {
-						log.WithError(err).Error("Could not generate markdown changelog")
-					} else {
-						log.Infof("Wrote changelog to %s", filename)
-					}
-				}
-			} else if !pushToGithub && !pushToSlack {
-				buf := new(bytes.Buffer)
-				if err := release.GenerateMarkdownChangelog(buf); err != nil {
-					log.WithError(err).Error("Could not generate markdown changelog")
-				} else {
-					fmt.Println(buf.String())
-				}
-			}
-		}
+		outputReleases(releases)
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(changelogCmd)
-	changelogCmd.Flags().BoolVar(&pushToGithub, "push", false, "Push changelog to Github instead of saving it locally")
-	changelogCmd.Flags().BoolVar(&overwrite, "overwrite", false, "Overwrite existing tags in Github if necessary")
-	changelogCmd.Flags().BoolVarP(&saveMarkdown, "save", "s", false, "Save the release notes to files")
-	changelogCmd.Flags().StringVarP(&slackChannel, "slack-channel", "c", "", "Post release notes to a slack channel")
-	changelogCmd.Flags().StringVarP(&slackWebhookURL, "slack-webhook", "w", "", "A slack webhook URL")
-	changelogCmd.Flags().StringVarP(&slackIconURL, "slack-icon", "i", "", "A URL containing the icon which will appear in the slack message")
+	addPushFlags(changelogCmd)
+	addOutputFlags(changelogCmd)
+	changelogCmd.Flags().StringVar(&since, "since", "", "Generate the changelog for pull requests merged from this time. Example: 2006-01-02T15:04")
+	changelogCmd.Flags().StringVar(&until, "until", "", "Generate the changelog for pull requests merged until this time (default now)")
+	changelogCmd.Flags().StringVar(&timezone, "timezone", "Local", "The time zone of --since and --until timestamps without one. Example: Europe/Oslo")
+	changelogCmd.Flags().StringVar(&baseBranch, "base-branch", "develop", "The branch pull requests are merged into, used with --since")
+}
+
+// addPushFlags adds the flags for pushing release notes to Github
+func addPushFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&pushToGithub, "push", false, "Push changelog to Github instead of saving it locally")
+	cmd.Flags().BoolVar(&diffGithub, "diff", false, "Print the diff of the release notes in Github and the generated ones instead of pushing them. Exits with 2 if they differ")
+	cmd.Flags().BoolVar(&draft, "draft", false, "Push releases as drafts to review before running publish. Existing drafts are updated")
+	cmd.Flags().StringSliceVar(&attachFormats, "attach", nil, "Formats of the changelog to attach to pushed releases, like markdown,html,json")
+	cmd.Flags().StringArrayVar(&assets, "asset", nil, "Files to attach to pushed releases, as a glob like 'dist/*.tar.gz'. Can be repeated")
+	addOverwriteFlags(cmd)
+}
+
+// addOverwriteFlags adds the flags for updating existing releases in Github
+func addOverwriteFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&overwrite, "overwrite", false, "Update the notes of existing releases in Github in place")
+	cmd.Flags().BoolVar(&recreate, "recreate", false, "Delete and recreate existing releases in Github instead of updating them. Loses their assets")
+}
+
+// addSlackFlags adds the flags for posting release notes to slack
+func addSlackFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&slackChannel, "slack-channel", "c", "", "Post release notes to a slack channel")
+	cmd.Flags().StringVarP(&slackWebhookURL, "slack-webhook", "w", "", "A slack webhook URL")
+	cmd.Flags().StringVarP(&slackIconURL, "slack-icon", "i", "", "A URL containing the icon which will appear in the slack message")
+}
+
+func pushOptions() release.PushOptions {
+	return release.PushOptions{
+		Overwrite: overwrite,
+		Recreate:  recreate,
+		Draft:     draft,
+		Formats:   attachFormats,
+		Assets:    assets,
+	}
+}
+
+// addOutputFlags adds the flags for rendering and saving release notes, and posting them to slack
+func addOutputFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVarP(&saveMarkdown, "save", "s", false, "Save the release notes to files")
+	addSlackFlags(cmd)
+	cmd.Flags().StringVar(&outputFormat, "format", release.Markdown, "The format of saved and printed release notes: markdown, html, json, yaml or slack-json")
+	cmd.Flags().StringVar(&updateFile, "update-file", "", "Add the release notes to a Keep a Changelog style file, like CHANGELOG.md")
+	cmd.Flags().BoolVar(&commitFile, "commit", false, "Commit the --update-file to the repository through Github instead of updating it locally")
+	cmd.Flags().StringVar(&commitBranch, "commit-branch", "develop", "The branch to commit the changelog to, or to open the pull request against")
+	cmd.Flags().BoolVar(&commitPR, "commit-pr", false, "Commit the changelog to a new branch and open a pull request against --commit-branch")
+	cmd.Flags().StringVar(&commitMessage, "commit-message", "", "The message of the changelog commit (default \"Update <file> for <release>\")")
+	cmd.Flags().StringVar(&commitAuthor, "commit-author", "", "The name of the changelog commit author. Required with --commit-email (default the owner of the access token)")
+	cmd.Flags().StringVar(&commitEmail, "commit-email", "", "The email of the changelog commit author. Required with --commit-author")
+	cmd.Flags().StringVar(&templateFile, "template", "", "A Go text/template file used to render the markdown release notes")
+	addLedgerFlags(cmd)
 }
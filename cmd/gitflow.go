@@ -0,0 +1,254 @@
+// Copyright © 2019 Franz von der Lippe franz.vonderlippe@gmail.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/franzwilhelm/gitflow-release-notes/gitflow"
+	"github.com/franzwilhelm/gitflow-release-notes/githubutil"
+	"github.com/franzwilhelm/gitflow-release-notes/release"
+	version "github.com/hashicorp/go-version"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	mergeBranch  bool
+	branchTitles = map[string]string{
+		gitflow.Release: "Release",
+		gitflow.Hotfix:  "Hotfix",
+	}
+)
+
+// releaseCmd represents the release command
+var releaseCmd = &cobra.Command{
+	Use:   "release",
+	Short: "Starts and finishes GitFlow release branches",
+}
+
+// hotfixCmd represents the hotfix command
+var hotfixCmd = &cobra.Command{
+	Use:   "hotfix",
+	Short: "Starts and finishes GitFlow hotfix branches",
+}
+
+var releaseStartCmd = &cobra.Command{
+	Use:   "start [tag]",
+	Short: "Creates a release branch from develop",
+	Long:  "Creates a release branch from develop. The tag defaults to the suggested next version of the unreleased changes",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		tagName := ""
+		if len(args) > 0 {
+			tagName = args[0]
+		} else {
+			unreleased, err := release.GenerateUnreleased(stableBranch, developBranch)
+			if err != nil {
+				logrus.WithError(err).Fatal("Could not generate unreleased changes")
+			}
+			suggestion, err := unreleased.SuggestVersion()
+			if err == release.ErrNothingToRelease {
+				logrus.Fatalf("Nothing to release, since no features, fixes or breaking changes were merged into %s after %s", developBranch, unreleased.Base)
+			} else if err != nil {
+				logrus.WithError(err).Fatal("Could not suggest the next version")
+			}
+			tagName = suggestion.Next
+		}
+		startBranch(gitflow.Release, developBranch, tagName)
+	},
+}
+
+var hotfixStartCmd = &cobra.Command{
+	Use:   "start [tag]",
+	Short: "Creates a hotfix branch from master",
+	Long:  "Creates a hotfix branch from master. The tag defaults to a patch bump of the latest tag on master",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		tagName := ""
+		if len(args) > 0 {
+			tagName = args[0]
+		} else {
+			latest, err := release.LatestTag(stableBranch)
+			if err != nil {
+				logrus.WithError(err).Fatal("Could not find the latest tag")
+			}
+			if tagName, err = release.NextVersion(latest.Data.Name, release.Patch); err != nil {
+				logrus.WithError(err).Fatal("Could not suggest the next version")
+			}
+		}
+		startBranch(gitflow.Hotfix, stableBranch, tagName)
+	},
+}
+
+var releaseFinishCmd = &cobra.Command{
+	Use:     "finish [tag]",
+	Short:   "Merges a release branch into master and develop, and tags the release",
+	Args:    cobra.ExactArgs(1),
+	PreRunE: initOutput,
+	Run: func(cmd *cobra.Command, args []string) {
+		finishBranch(gitflow.Release, args[0])
+	},
+}
+
+var hotfixFinishCmd = &cobra.Command{
+	Use:     "finish [tag]",
+	Short:   "Merges a hotfix branch into master and develop, and tags the release",
+	Args:    cobra.ExactArgs(1),
+	PreRunE: initOutput,
+	Run: func(cmd *cobra.Command, args []string) {
+		finishBranch(gitflow.Hotfix, args[0])
+	},
+}
+
+// branchName returns the GitFlow branch name for a tag.
+// For instance prefix 'release' and tag 'v1.2.3' returns 'release/1.2.3'
+func branchName(prefix, tagName string) string {
+	v, err := version.NewVersion(tagName)
+	if err != nil {
+		logrus.WithError(err).Fatalf("Tag %s could not be semver validated", tagName)
+	}
+	return fmt.Sprintf("%s/%s", prefix, v.String())
+}
+
+func startBranch(prefix, from, tagName string) {
+	branch := branchName(prefix, tagName)
+	log := logrus.WithFields(logrus.Fields{
+		"branch": branch,
+		"from":   from,
+	})
+	if dryRun {
+		release.DryRunf("would create branch %s from %s", branch, from)
+		return
+	}
+	if err := githubutil.CreateBranch(branch, from); err != nil {
+		log.WithError(err).Fatal("Could not create branch")
+	}
+	log.Info("Created branch")
+}
+
+// finishBranch merges the branch into master, tags the merge commit and merges
+// the branch into develop. Steps that are already done are skipped, so finish
+// can be run again if a merge fails
+func finishBranch(prefix, tagName string) {
+	branch := branchName(prefix, tagName)
+	title := fmt.Sprintf("%s %s", branchTitles[prefix], tagName)
+	log := logrus.WithFields(logrus.Fields{
+		"tag":    tagName,
+		"branch": branch,
+	})
+
+	tagSha := mergeBranchInto(branch, stableBranch, title)
+	if mergeBranch {
+		createTag(tagName, tagSha)
+	}
+	mergeBranchInto(branch, developBranch, title)
+
+	if !mergeBranch {
+		log.Info("Run finish with --merge when the pull requests are approved to merge them and tag the release")
+		return
+	}
+	if dryRun {
+		release.DryRunf("would generate the changelog for %s", tagName)
+		return
+	}
+	releases, err := release.GenerateReleasesBetweenRefs(tagName, tagName)
+	if err != nil {
+		log.WithError(err).Fatal("Could not generate releases")
+	}
+	outputReleases(releases)
+}
+
+// createTag tags the merge commit into master, unless the tag already exists
+func createTag(tagName, sha string) {
+	log := logrus.WithField("tag", tagName)
+	exists, err := githubutil.TagExists(tagName)
+	if err != nil {
+		log.WithError(err).Fatal("Could not look up tag")
+	}
+	if exists {
+		log.Info("Tag already exists")
+		return
+	}
+	if dryRun {
+		release.DryRunf("would tag the merge commit into %s as %s", stableBranch, tagName)
+		return
+	}
+	if err := githubutil.CreateTag(tagName, sha); err != nil {
+		log.WithError(err).Fatal("Could not create tag")
+	}
+	log.Info("Created tag")
+}
+
+// mergeBranchInto opens a pull request from the head branch to the base branch
+// if there isn't one already, and merges it if --merge is used.
+// Returns the SHA of the merge commit. If the pull request was merged by an
+// earlier run, its merge commit is returned instead
+func mergeBranchInto(head, base, title string) string {
+	log := logrus.WithFields(logrus.Fields{
+		"head": head,
+		"base": base,
+	})
+	pr, err := githubutil.FindOpenPullRequest(head, base)
+	if err != nil {
+		log.WithError(err).Fatal("Could not look up pull requests")
+	}
+	if pr == nil {
+		merged, err := githubutil.FindMergedPullRequest(head, base)
+		if err != nil {
+			log.WithError(err).Fatal("Could not look up pull requests")
+		}
+		if merged != nil {
+			log.Infof("Pull request #%v is already merged", merged.GetNumber())
+			return merged.GetMergeCommitSHA()
+		}
+		if dryRun {
+			release.DryRunf("would open pull request from %s into %s", head, base)
+		} else if pr, err = githubutil.CreatePullRequest(head, base, title, ""); err != nil {
+			log.WithError(err).Fatal("Could not open pull request")
+		} else {
+			log.Infof("Opened pull request #%v", pr.GetNumber())
+		}
+	}
+	if !mergeBranch {
+		return ""
+	}
+	if dryRun {
+		release.DryRunf("would merge pull request from %s into %s", head, base)
+		return ""
+	}
+	sha, err := githubutil.MergePullRequest(pr.GetNumber(), title)
+	if err != nil {
+		log.WithError(err).Fatalf("Could not merge pull request #%v", pr.GetNumber())
+	}
+	log.Infof("Merged pull request #%v", pr.GetNumber())
+	return sha
+}
+
+func init() {
+	rootCmd.AddCommand(releaseCmd, hotfixCmd)
+	releaseCmd.AddCommand(releaseStartCmd, releaseFinishCmd)
+	hotfixCmd.AddCommand(hotfixStartCmd, hotfixFinishCmd)
+
+	for _, cmd := range []*cobra.Command{releaseCmd, hotfixCmd} {
+		cmd.PersistentFlags().StringVar(&stableBranch, "master", "master", "The stable branch where tags are pushed")
+		cmd.PersistentFlags().StringVar(&developBranch, "develop", "develop", "The branch containing the unreleased changes")
+	}
+	for _, cmd := range []*cobra.Command{releaseFinishCmd, hotfixFinishCmd} {
+		cmd.Flags().BoolVar(&mergeBranch, "merge", false, "Merge the pull requests and tag the merge commit, instead of only opening them")
+		addPushFlags(cmd)
+		addOutputFlags(cmd)
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright © 2019 Franz von der Lippe franz.vonderlippe@gmail.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/franzwilhelm/gitflow-release-notes/release"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var changelogFile string
+
+// importCmd represents the import command
+var importCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Creates Github releases from the sections of a changelog file",
+	Long: `Creates Github releases from the version sections of a Keep a Changelog
+style file, like CHANGELOG.md. Sections are matched with tags by version, and
+existing releases are only updated with --overwrite or --recreate. Tags without a section
+and sections without a tag are reported.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		content, err := ioutil.ReadFile(changelogFile)
+		if err != nil {
+			logrus.WithError(err).Fatal("Could not read changelog")
+		}
+		release.Force = forcePublish
+		result, err := release.ImportChangelog(string(content), pushOptions())
+		if err != nil {
+			logrus.WithError(err).Fatal("Could not import changelog")
+		}
+
+		logrus.Infof("Matched %v sections with tags", len(result.Matched))
+		if len(result.SectionsWithoutTags) > 0 {
+			logrus.Warnf("Sections without a tag: %s", strings.Join(result.SectionsWithoutTags, ", "))
+		}
+		if len(result.TagsWithoutSection) > 0 {
+			logrus.Warnf("Tags without a section: %s", strings.Join(result.TagsWithoutSection, ", "))
+		}
+		if len(result.Failed) > 0 {
+			logrus.Errorf("Could not push releases: %s", strings.Join(result.Failed, ", "))
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+	importCmd.Flags().StringVar(&changelogFile, "input", "CHANGELOG.md", "The changelog file to import")
+	addOverwriteFlags(importCmd)
+	importCmd.Flags().BoolVar(&forcePublish, "force", false, "Replace Github release bodies without the generated notes markers")
+}
@@ -0,0 +1,94 @@
+// Copyright © 2019 Franz von der Lippe franz.vonderlippe@gmail.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/franzwilhelm/gitflow-release-notes/githubutil"
+	"github.com/franzwilhelm/gitflow-release-notes/ledger"
+	"github.com/franzwilhelm/gitflow-release-notes/release"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	ledgerFile   string
+	ledgerBranch string
+	forcePublish bool
+)
+
+// githubLedgerStore stores the ledger in a branch of the repository, so it's
+// kept between CI jobs. Each recorded publication is committed
+type githubLedgerStore struct {
+	path   string
+	branch string
+}
+
+func (s githubLedgerStore) Load() (string, error) {
+	content, _, err := githubutil.GetFileContent(s.path, s.branch)
+	return content, err
+}
+
+func (s githubLedgerStore) Save(content string) error {
+	// The file is fetched again for its current SHA, which changes with every commit
+	_, sha, err := githubutil.GetFileContent(s.path, s.branch)
+	if err != nil {
+		return err
+	}
+	_, err = githubutil.CommitFile(s.path, s.branch, "Update "+s.path, content, sha, nil)
+	return err
+}
+
+// addLedgerFlags adds the flags for the ledger of published releases
+func addLedgerFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&ledgerFile, "ledger", "", "A JSON file recording the releases published to Github and Slack. Unchanged releases are not published again")
+	cmd.Flags().StringVar(&ledgerBranch, "ledger-branch", "", "Read the ledger from this branch and commit it there through the Github API, instead of using a local file")
+	cmd.Flags().BoolVar(&forcePublish, "force", false, "Publish releases even if the ledger shows they are unchanged, and replace Github release bodies without the generated notes markers")
+}
+
+// initLedger validates the ledger options, which can also be set in the config file
+func initLedger() error {
+	if ledgerFile == "" {
+		ledgerFile = viper.GetString("ledger")
+	}
+	if ledgerBranch == "" {
+		ledgerBranch = viper.GetString("ledger_branch")
+	}
+	release.Force = forcePublish
+	if ledgerFile != "" && ledgerBranch != "" {
+		_, err := repositoryPath(ledgerFile)
+		return err
+	}
+	return nil
+}
+
+// loadLedger opens the ledger in the local file, or in the ledger branch in
+// Github. A missing file is an empty ledger. Publications are saved to the
+// ledger as soon as they are recorded, so an interrupted run keeps them
+func loadLedger() {
+	if ledgerFile == "" {
+		return
+	}
+	var store ledger.Store = ledger.FileStore(ledgerFile)
+	if ledgerBranch != "" {
+		path, _ := repositoryPath(ledgerFile)
+		store = githubLedgerStore{path: path, branch: ledgerBranch}
+	}
+	l, err := ledger.Open(store)
+	if err != nil {
+		logrus.WithError(err).WithField("ledger", ledgerFile).Fatal("Could not read ledger")
+	}
+	release.Ledger = l
+}
@@ -0,0 +1,68 @@
+// Copyright © 2019 Franz von der Lippe franz.vonderlippe@gmail.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/franzwilhelm/gitflow-release-notes/release"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var outputJSON bool
+
+// nextVersionCmd represents the next-version command
+var nextVersionCmd = &cobra.Command{
+	Use:   "next-version",
+	Short: "Suggests the next version based on the unreleased pull requests",
+	Long: `Suggests the next version based on the unreleased pull requests.
+
+The major version is bumped if any pull request is breaking, either by label
+or by a BREAKING CHANGE: footer in its body. The minor version is bumped if
+there are features, and the patch version if there are bug fixes or
+hotfixes. Other pull requests don't call for a release on their own.
+Exits with 1 if there is nothing to release.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		unreleased, err := release.GenerateUnreleased(stableBranch, developBranch)
+		if err != nil {
+			logrus.WithError(err).Fatal("Could not generate unreleased changes")
+		}
+		suggestion, err := unreleased.SuggestVersion()
+		if err == release.ErrNothingToRelease {
+			logrus.Fatalf("Nothing to release, since no features, fixes or breaking changes were merged into %s after %s", developBranch, unreleased.Base)
+		} else if err != nil {
+			logrus.WithError(err).Fatal("Could not suggest the next version")
+		}
+		if !outputJSON {
+			fmt.Println(suggestion.Next)
+			return
+		}
+		raw, err := json.Marshal(suggestion)
+		if err != nil {
+			logrus.WithError(err).Fatal("Could not marshal version suggestion")
+		}
+		fmt.Println(string(raw))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(nextVersionCmd)
+	nextVersionCmd.Flags().StringVar(&stableBranch, "master", "master", "The stable branch where tags are pushed")
+	nextVersionCmd.Flags().StringVar(&developBranch, "develop", "develop", "The branch containing the unreleased changes")
+	nextVersionCmd.Flags().BoolVar(&outputJSON, "json", false, "Output the current version, next version and bump as JSON")
+}
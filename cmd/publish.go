@@ -0,0 +1,83 @@
+// Copyright © 2019 Franz von der Lippe franz.vonderlippe@gmail.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/franzwilhelm/gitflow-release-notes/release"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var renderBeforePublish bool
+
+// publishCmd represents the publish command
+var publishCmd = &cobra.Command{
+	Use:   "publish [tag]",
+	Short: "Publishes a draft release in Github",
+	Long: `Publishes a draft release pushed with --push --draft, after it has been
+reviewed in Github. With --render the release notes are generated again and
+replace the draft notes before publishing. The slack flags post the release
+notes to slack once the release is published. These are the notes reviewed
+in Github, or the generated ones if --render updated the draft.`,
+	Args:    cobra.ExactArgs(1),
+	PreRunE: initOutput,
+	Run: func(cmd *cobra.Command, args []string) {
+		tagName := args[0]
+		log := logrus.WithField("tag", tagName)
+
+		loadLedger()
+
+		var r *release.Release
+		if renderBeforePublish {
+			releases, err := release.GenerateReleasesBetweenRefs(tagName, tagName)
+			if err != nil {
+				log.WithError(err).Fatal("Could not generate release")
+			} else if len(releases) == 0 {
+				log.Fatal("Could not find the release of the tag")
+			}
+			r = &releases[len(releases)-1]
+			pushed, err := r.PushToGithub(release.PushOptions{Draft: true})
+			if err != nil {
+				log.WithError(err).Fatal("Could not update the draft release")
+			} else if !pushed {
+				// The notes in Github are posted instead, since the generated ones weren't pushed
+				r = nil
+			}
+		}
+		notes, err := release.PublishDraft(tagName)
+		if err != nil {
+			log.WithError(err).Fatal("Could not publish release")
+		}
+		if slackChannel == "" {
+			return
+		}
+		log.Info("Pushing release to slack")
+		if r != nil {
+			err = r.PushToSlack(slackChannel, slackIconURL)
+		} else {
+			err = release.PushNotesToSlack(tagName, notes, slackChannel, slackIconURL)
+		}
+		if err != nil {
+			log.WithError(err).Error("Could not push release to slack")
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(publishCmd)
+	publishCmd.Flags().BoolVar(&renderBeforePublish, "render", false, "Generate the release notes again and update the draft before publishing it")
+	addSlackFlags(publishCmd)
+	addLedgerFlags(publishCmd)
+}
@@ -0,0 +1,160 @@
+// Copyright © 2019 Franz von der Lippe franz.vonderlippe@gmail.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/franzwilhelm/gitflow-release-notes/release"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	yaml "gopkg.in/yaml.v2"
+)
+
+var inputFile string
+
+// readDocuments reads the release documents of a JSON or YAML file, or JSON
+// from stdin if the path is -. A file can contain several documents, like the
+// output of changelog for a range of tags, and a document can be a list of
+// releases
+func readDocuments(path string) ([]release.Document, error) {
+	in := os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		in = f
+	}
+	ext := filepath.Ext(path)
+	if ext == ".yaml" || ext == ".yml" {
+		return readYAMLDocuments(in)
+	}
+	return readJSONDocuments(in)
+}
+
+// readJSONDocuments reads a stream of JSON documents, like JSON Lines
+func readJSONDocuments(in io.Reader) ([]release.Document, error) {
+	var docs []release.Document
+	decoder := json.NewDecoder(in)
+	for {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err == io.EOF {
+			return docs, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if raw = bytes.TrimSpace(raw); len(raw) > 0 && raw[0] == '[' {
+			var list []release.Document
+			if err := json.Unmarshal(raw, &list); err != nil {
+				return nil, err
+			}
+			docs = append(docs, list...)
+			continue
+		}
+		var doc release.Document
+		if err := json.Unmarshal(raw, &doc); err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+}
+
+// readYAMLDocuments reads a stream of YAML documents separated by ---.
+// Keys that are set twice in a document are an error, since releases
+// concatenated without --- would otherwise be read as the last one only
+func readYAMLDocuments(in io.Reader) ([]release.Document, error) {
+	var docs []release.Document
+	decoder := yaml.NewDecoder(in)
+	decoder.SetStrict(true)
+	for {
+		var value interface{}
+		if err := decoder.Decode(&value); err == io.EOF {
+			return docs, nil
+		} else if err != nil {
+			return nil, err
+		} else if value == nil {
+			continue
+		}
+		raw, err := yaml.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := value.([]interface{}); ok {
+			var list []release.Document
+			if err := yaml.Unmarshal(raw, &list); err != nil {
+				return nil, err
+			}
+			docs = append(docs, list...)
+			continue
+		}
+		var doc release.Document
+		if err := yaml.Unmarshal(raw, &doc); err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+}
+
+// renderCmd represents the render command
+var renderCmd = &cobra.Command{
+	Use:   "render",
+	Short: "Renders or publishes releases saved with --format json or yaml",
+	Long: `Renders or publishes releases saved with --format json or yaml.
+
+The sections, titles and bodies of the saved releases are used as they are,
+so they can be edited by hand before the curated release notes are rendered
+or published. Github is only used with --push, --diff, --commit or
+--ledger-branch, and the repository of the first release is used if
+--repository is not set.`,
+	Args: cobra.NoArgs,
+	// Github is initialized only when pushing, diffing, committing or using the ledger branch
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {},
+	PreRunE:          initOutput,
+	Run: func(cmd *cobra.Command, args []string) {
+		docs, err := readDocuments(inputFile)
+		if err != nil {
+			logrus.WithError(err).Fatal("Could not read releases")
+		}
+		var releases []release.Release
+		for _, doc := range docs {
+			r, err := release.FromDocument(doc)
+			if err != nil {
+				logrus.WithError(err).Fatal("Could not read release")
+			}
+			releases = append(releases, *r)
+		}
+		if pushToGithub || diffGithub || commitFile || ledgerBranch != "" {
+			if repo.Name == "" && len(releases) > 0 {
+				repo = releases[0].Repository
+			}
+			initGithub()
+		}
+		outputReleases(releases)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(renderCmd)
+	renderCmd.Flags().StringVar(&inputFile, "input", "", "A JSON or YAML file with releases, or - to read JSON from stdin")
+	renderCmd.MarkFlagRequired("input")
+	addPushFlags(renderCmd)
+	addOutputFlags(renderCmd)
+}
@@ -21,6 +21,7 @@ import (
 	"os"
 
 	"github.com/franzwilhelm/gitflow-release-notes/githubutil"
+	"github.com/franzwilhelm/gitflow-release-notes/release"
 	homedir "github.com/mitchellh/go-homedir"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -29,6 +30,7 @@ import (
 
 var (
 	cfgFile    string
+	dryRun     bool
 	repo       githubutil.Repository
 	ctx        context.Context
 	httpClient *http.Client
@@ -39,27 +41,42 @@ var rootCmd = &cobra.Command{
 	Use:   "gitflow-release-notes",
 	Short: "Automatically generate release notes based on pull requests",
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
-		accessToken := os.Getenv("GITHUB_ACCESS_TOKEN")
-		if accessToken == "" {
-			logrus.Fatal("GITHUB_ACCESS_TOKEN empty, or not set")
-		}
-		githubutil.Initialize(accessToken, repo)
+		initGithub()
 	},
 }
 
+// initGithub initializes the Github clients for the repository
+func initGithub() {
+	if repo.Name == "" {
+		logrus.Fatal("--repository is required")
+	}
+	accessToken := os.Getenv("GITHUB_ACCESS_TOKEN")
+	if accessToken == "" {
+		logrus.Fatal("GITHUB_ACCESS_TOKEN empty, or not set")
+	}
+	githubutil.Initialize(accessToken, repo)
+}
+
+// exitChanged is the exit code of dry runs and diffs that found changes, so
+// CI can tell them from errors, which exit with 1
+const exitChanged = 2
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
 		os.Exit(1)
 	}
+	if release.WouldChange() {
+		os.Exit(exitChanged)
+	}
 }
 
 func init() {
 	cobra.OnInitialize(initConfig)
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.gitflow-release-notes.yaml)")
 	rootCmd.PersistentFlags().VarP(&repo, "repository", "r", "Github repository ref with owner. Example: franzwilhelm/gitflow-release-notes")
-	rootCmd.MarkPersistentFlagRequired("repository")
+	rootCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "Print the changes to Github, Slack and the changelog file instead of making them. Exits with 2 if anything would change")
 }
 
 // initConfig reads in config file and ENV variables if set.
@@ -86,4 +103,34 @@ func initConfig() {
 	if err := viper.ReadInConfig(); err == nil {
 		fmt.Println("Using config file:", viper.ConfigFileUsed())
 	}
+
+	if viper.IsSet("exclude_labels") {
+		release.ExcludeLabels = viper.GetStringSlice("exclude_labels")
+	}
+	release.LabelPrecedence = viper.GetBool("label_precedence")
+	release.ConventionalCommits = viper.GetBool("conventional_commits")
+	release.WrapMentions = viper.GetBool("wrap_mentions")
+	release.DryRun = dryRun
+	if viper.IsSet("body") {
+		var extraction release.BodyExtraction
+		if err := viper.UnmarshalKey("body", &extraction); err != nil {
+			fmt.Println("Could not read body extraction from config:", err)
+			os.Exit(1)
+		}
+		if err := release.UseBodyExtraction(extraction); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+	if viper.IsSet("sections") {
+		var rules []release.SectionRule
+		if err := viper.UnmarshalKey("sections", &rules); err != nil {
+			fmt.Println("Could not read sections from config:", err)
+			os.Exit(1)
+		}
+		if err := release.UseSectionRules(rules); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
 }
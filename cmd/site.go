@@ -0,0 +1,59 @@
+// Copyright © 2019 Franz von der Lippe franz.vonderlippe@gmail.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/franzwilhelm/gitflow-release-notes/release"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var siteDir string
+
+// siteCmd represents the site command
+var siteCmd = &cobra.Command{
+	Use:   "site [base-ref..head-ref]",
+	Short: "Generates a static site with the releases of a ref range",
+	Long: `Generates a static site with the releases of a ref range.
+
+The site has an index of the releases, a self-contained page for each of them
+with the permalink <release>/, and their documents as JSON, both for each
+release and for all of them in releases.json.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		base, head, err := parseRefInput(args[0])
+		if err != nil {
+			logrus.WithError(err).Fatal("Could not parse ref input")
+		}
+		logrus.WithFields(logrus.Fields{
+			"repo":  repo.Name,
+			"owner": repo.Owner,
+		}).Infof("Generating site for refs between %s and %s", base, head)
+
+		releases, err := release.GenerateReleasesBetweenRefs(base, head)
+		if err != nil {
+			logrus.WithError(err).Fatal("Could not generate releases")
+		}
+		if err := release.GenerateSite(siteDir, repo, releases); err != nil {
+			logrus.WithError(err).Fatal("Could not generate site")
+		}
+		logrus.Infof("Wrote site to %s", siteDir)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(siteCmd)
+	siteCmd.Flags().StringVarP(&siteDir, "output", "o", "site", "The directory to write the site to")
+}
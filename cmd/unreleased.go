@@ -0,0 +1,54 @@
+// Copyright © 2019 Franz von der Lippe franz.vonderlippe@gmail.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/franzwilhelm/gitflow-release-notes/release"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	stableBranch  string
+	developBranch string
+)
+
+// unreleasedCmd represents the unreleased command
+var unreleasedCmd = &cobra.Command{
+	Use:     "unreleased",
+	Short:   "Generates a changelog of what would ship in the next release",
+	Long:    "Generates a changelog for the changes on the develop branch since the newest tag reachable from the stable branch",
+	Args:    cobra.NoArgs,
+	PreRunE: initOutput,
+	Run: func(cmd *cobra.Command, args []string) {
+		logrus.WithFields(logrus.Fields{
+			"repo":  repo.Name,
+			"owner": repo.Owner,
+		}).Infof("Generating changelog for unreleased changes on %s", developBranch)
+
+		unreleased, err := release.GenerateUnreleased(stableBranch, developBranch)
+		if err != nil {
+			logrus.WithError(err).Fatal("Could not generate unreleased changes")
+		}
+		outputReleases([]release.Release{*unreleased})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(unreleasedCmd)
+	unreleasedCmd.Flags().StringVar(&stableBranch, "master", "master", "The stable branch where tags are pushed")
+	unreleasedCmd.Flags().StringVar(&developBranch, "develop", "develop", "The branch containing the unreleased changes")
+	addOutputFlags(unreleasedCmd)
+}
@@ -0,0 +1,51 @@
+package conventional
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	headerPattern  = regexp.MustCompile(`^(\w+)(?:\(([^)]*)\))?(!)?:\s+(.+)$`)
+	prNumberSuffix = regexp.MustCompile(`\s+\(#\d+\)$`)
+)
+
+// Message holds the parts of a Conventional Commits message or pull request title.
+// Example input: feat(api)!: drop v1 endpoints
+type Message struct {
+	Type        string
+	Scope       string
+	Breaking    bool
+	Description string
+}
+
+// Parse parses the first line of a commit message or pull request title.
+// The pull request number Github appends to squash commits is removed.
+// Returns false if the line doesn't follow Conventional Commits
+func Parse(s string) (Message, bool) {
+	line := strings.TrimSpace(strings.SplitN(s, "\n", 2)[0])
+	line = prNumberSuffix.ReplaceAllString(line, "")
+	match := headerPattern.FindStringSubmatch(line)
+	if match == nil {
+		return Message{}, false
+	}
+	return Message{
+		Type:        strings.ToLower(match[1]),
+		Scope:       strings.TrimSpace(match[2]),
+		Breaking:    match[3] == "!",
+		Description: match[4],
+	}, true
+}
+
+// Title returns the description with the first letter capitalized
+// Example input: drop v1 endpoints
+// Example output: Drop v1 endpoints
+func (m Message) Title() string {
+	if m.Description == "" {
+		return ""
+	}
+	r, size := utf8.DecodeRuneInString(m.Description)
+	return string(unicode.ToUpper(r)) + m.Description[size:]
+}
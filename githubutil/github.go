@@ -3,6 +3,12 @@ package githubutil
 import (
 	"context"
 	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -16,7 +22,7 @@ import (
 var (
 	// Repo is the repository used for fetching of data with the Github clients
 	Repo             Repository
-	githubDateFormat = "2006-01-02"
+	githubTimeFormat = time.RFC3339
 	ctx              = context.Background()
 	client           *github.Client
 	clientv4         *githubv4.Client
@@ -38,21 +44,13 @@ func Initialize(accessToken string, r Repository) {
 // GetPullRequestIssuesBetween fetches all pull request issues between two timestamps,
 // using the Github Search api. Returns a map of the merge commit SHAs and the issues.
 func GetPullRequestIssuesBetween(start, end time.Time) (map[string]github.Issue, error) {
-	startFormatted := start.Format(githubDateFormat)
-	endFormatted := end.Format(githubDateFormat)
-	logrus.Infof("Fetching all pull requests between %s and %s. This may take a while...", startFormatted, endFormatted)
-	query := searchQuery(map[string]interface{}{
-		"repo":   fmt.Sprintf("%s/%s", Repo.Owner, Repo.Name),
-		"type":   "pr",
-		"merged": fmt.Sprintf("%s..%s", startFormatted, endFormatted),
-	})
-	result, _, err := client.Search.Issues(ctx, query, nil)
+	issues, err := searchMergedPullRequests("", start, end)
 	if err != nil {
 		return nil, err
 	}
 	prMap := make(map[string]github.Issue)
 
-	for _, issue := range result.Issues {
+	for _, issue := range issues {
 		var graphqlResult struct {
 			Repository struct {
 				PullRequest struct {
@@ -77,6 +75,63 @@ func GetPullRequestIssuesBetween(start, end time.Time) (map[string]github.Issue,
 	return prMap, nil
 }
 
+// GetPullRequestsMergedBetween fetches all pull requests merged into the base
+// branch between two timestamps. The start is inclusive and the end exclusive
+func GetPullRequestsMergedBetween(base string, start, end time.Time) ([]github.PullRequest, error) {
+	issues, err := searchMergedPullRequests(base, start, end)
+	if err != nil {
+		return nil, err
+	}
+	var prs []github.PullRequest
+	for _, issue := range issues {
+		pr, _, err := client.PullRequests.Get(ctx, Repo.Owner, Repo.Name, issue.GetNumber())
+		if err != nil {
+			return nil, err
+		}
+		// The search api compares with second precision, so filter on the exact merge time
+		if pr.MergedAt == nil || pr.MergedAt.Before(start) || !pr.MergedAt.Before(end) {
+			continue
+		}
+		prs = append(prs, *pr)
+	}
+	sort.Slice(prs, func(i, j int) bool {
+		return prs[i].MergedAt.Before(*prs[j].MergedAt)
+	})
+	logrus.Info("Done fetching all pull requests")
+	return prs, nil
+}
+
+// searchMergedPullRequests searches for pull requests merged between two timestamps,
+// and into the base branch if it's not empty. The timestamps keep their time zone
+func searchMergedPullRequests(base string, start, end time.Time) ([]github.Issue, error) {
+	startFormatted := start.Format(githubTimeFormat)
+	endFormatted := end.Format(githubTimeFormat)
+	logrus.Infof("Fetching all pull requests between %s and %s. This may take a while...", startFormatted, endFormatted)
+	searchMap := map[string]interface{}{
+		"repo":   Repo.Full(),
+		"type":   "pr",
+		"merged": fmt.Sprintf("%s..%s", startFormatted, endFormatted),
+	}
+	if base != "" {
+		searchMap["base"] = base
+	}
+	query := searchQuery(searchMap)
+
+	var issues []github.Issue
+	opt := &github.SearchOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		result, response, err := client.Search.Issues(ctx, query, opt)
+		if err != nil {
+			return nil, err
+		}
+		issues = append(issues, result.Issues...)
+		if response.NextPage == 0 {
+			return issues, nil
+		}
+		opt.Page = response.NextPage
+	}
+}
+
 // GetPullRequests fetches the 100 most recent pull requests
 func GetPullRequests() (map[string]*github.PullRequest, error) {
 	logrus.Infof("Fetching latest 100 pull requests")
@@ -121,6 +176,12 @@ func (t *Tag) IsBetween(base, head *version.Version) bool {
 	return base.LessThan(t.Version) && (head.GreaterThan(t.Version) || head.Equal(t.Version))
 }
 
+// Prefix returns the part of the tag name in front of the version.
+// For instance tag 'v1.2.3' returns 'v'
+func (t *Tag) Prefix() string {
+	return strings.Replace(t.Data.Name, t.Version.String(), "", 1)
+}
+
 // GetTags fetches the 100 most recent tags
 func GetTags() ([]Tag, error) {
 	logrus.Info("Fetching the latest 100 tags")
@@ -142,17 +203,63 @@ func GetTags() ([]Tag, error) {
 
 	var tags []Tag
 	for _, edge := range edges {
-		urlSplit := strings.Split(edge.Node.Target.CommitURL, "/")
-		edge.Node.Target.Sha = urlSplit[len(urlSplit)-1]
-		tag := Tag{Data: edge.Node}
-		if tag.Version, err = version.NewVersion(edge.Node.Name); err != nil {
-			return nil, fmt.Errorf("tag %s could not be semver validated", tag.Data.Name)
+		tag, err := newTag(edge.Node)
+		if err != nil {
+			return nil, err
 		}
 		tags = append(tags, tag)
 	}
 	return tags, err
 }
 
+// GetAllTags fetches all tags, 100 at a time. Tags that aren't semver versions are left out
+func GetAllTags() ([]Tag, error) {
+	logrus.Info("Fetching all tags")
+	var graphqlResult struct {
+		Repository struct {
+			Tags struct {
+				Edges []struct {
+					Node graphqlTag
+				}
+				PageInfo struct {
+					EndCursor   githubv4.String
+					HasNextPage bool
+				}
+			} `graphql:"refs(refPrefix: \"refs/tags/\", first: 100, after: $cursor, direction: DESC)"`
+		} `graphql:"repository(owner: $owner, name: $repo)"`
+	}
+	query := graphqlQuery(map[string]interface{}{"cursor": (*githubv4.String)(nil)})
+	var tags []Tag
+	for {
+		if err := clientv4.Query(ctx, &graphqlResult, query); err != nil {
+			return nil, err
+		}
+		for _, edge := range graphqlResult.Repository.Tags.Edges {
+			tag, err := newTag(edge.Node)
+			if err != nil {
+				logrus.Debug(err)
+				continue
+			}
+			tags = append(tags, tag)
+		}
+		if !graphqlResult.Repository.Tags.PageInfo.HasNextPage {
+			return tags, nil
+		}
+		query["cursor"] = githubv4.NewString(graphqlResult.Repository.Tags.PageInfo.EndCursor)
+	}
+}
+
+func newTag(node graphqlTag) (Tag, error) {
+	urlSplit := strings.Split(node.Target.CommitURL, "/")
+	node.Target.Sha = urlSplit[len(urlSplit)-1]
+	tag := Tag{Data: node}
+	var err error
+	if tag.Version, err = version.NewVersion(node.Name); err != nil {
+		return tag, fmt.Errorf("tag %s could not be semver validated", tag.Data.Name)
+	}
+	return tag, nil
+}
+
 // CompareCommits returns all commits between two github tags or hashes
 func CompareCommits(base, head string) ([]github.RepositoryCommit, error) {
 	logrus.Infof("Fetching all commits between %s and %s", base, head)
@@ -163,20 +270,205 @@ func CompareCommits(base, head string) ([]github.RepositoryCommit, error) {
 	return comparison.Commits, nil
 }
 
-// CreateRelease creates a release in Github
-func CreateRelease(tagName, body string) error {
-	_, _, err := client.Repositories.CreateRelease(ctx, Repo.Owner, Repo.Name, &github.RepositoryRelease{
+// IsAncestor checks if the base ref is reachable from the head ref
+func IsAncestor(base, head string) (bool, error) {
+	comparison, _, err := client.Repositories.CompareCommits(ctx, Repo.Owner, Repo.Name, base, head)
+	if err != nil {
+		return false, err
+	}
+	status := comparison.GetStatus()
+	return status == "ahead" || status == "identical", nil
+}
+
+// CreateBranch creates a branch pointing to the head commit of another branch
+func CreateBranch(name, from string) error {
+	ref, _, err := client.Git.GetRef(ctx, Repo.Owner, Repo.Name, "heads/"+from)
+	if err != nil {
+		return err
+	}
+	_, _, err = client.Git.CreateRef(ctx, Repo.Owner, Repo.Name, &github.Reference{
+		Ref:    github.String("refs/heads/" + name),
+		Object: &github.GitObject{SHA: ref.Object.SHA},
+	})
+	return err
+}
+
+// CreateTag creates a lightweight tag pointing to a commit
+func CreateTag(name, sha string) error {
+	_, _, err := client.Git.CreateRef(ctx, Repo.Owner, Repo.Name, &github.Reference{
+		Ref:    github.String("refs/tags/" + name),
+		Object: &github.GitObject{SHA: &sha},
+	})
+	return err
+}
+
+// TagExists checks if a tag exists
+func TagExists(name string) (bool, error) {
+	refs, response, err := client.Git.GetRefs(ctx, Repo.Owner, Repo.Name, "tags/"+name)
+	if response != nil && response.StatusCode == http.StatusNotFound {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	// The API also returns the tags that start with the name if there is no exact match
+	for _, ref := range refs {
+		if ref.GetRef() == "refs/tags/"+name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// FindOpenPullRequest finds the open pull request from the head branch to the
+// base branch. Returns nil if there is none
+func FindOpenPullRequest(head, base string) (*github.PullRequest, error) {
+	prs, _, err := client.PullRequests.List(ctx, Repo.Owner, Repo.Name, &github.PullRequestListOptions{
+		State: "open",
+		Head:  fmt.Sprintf("%s:%s", Repo.Owner, head),
+		Base:  base,
+	})
+	if err != nil || len(prs) == 0 {
+		return nil, err
+	}
+	return prs[0], nil
+}
+
+// FindMergedPullRequest finds the merged pull request from the head branch to
+// the base branch. Returns nil if there is none
+func FindMergedPullRequest(head, base string) (*github.PullRequest, error) {
+	prs, _, err := client.PullRequests.List(ctx, Repo.Owner, Repo.Name, &github.PullRequestListOptions{
+		State: "closed",
+		Head:  fmt.Sprintf("%s:%s", Repo.Owner, head),
+		Base:  base,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, pr := range prs {
+		if pr.MergedAt != nil {
+			return pr, nil
+		}
+	}
+	return nil, nil
+}
+
+// CreatePullRequest opens a pull request from the head branch to the base branch
+func CreatePullRequest(head, base, title, body string) (*github.PullRequest, error) {
+	pr, _, err := client.PullRequests.Create(ctx, Repo.Owner, Repo.Name, &github.NewPullRequest{
+		Title: &title,
+		Head:  &head,
+		Base:  &base,
+		Body:  &body,
+	})
+	return pr, err
+}
+
+// MergePullRequest merges a pull request and returns the SHA of the merge commit
+func MergePullRequest(number int, commitMessage string) (string, error) {
+	result, _, err := client.PullRequests.Merge(ctx, Repo.Owner, Repo.Name, number, commitMessage, nil)
+	if err != nil {
+		return "", err
+	}
+	return result.GetSHA(), nil
+}
+
+// BranchExists checks if a branch exists
+func BranchExists(name string) (bool, error) {
+	_, response, err := client.Git.GetRef(ctx, Repo.Owner, Repo.Name, "heads/"+name)
+	if response != nil && response.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// GetFileContent fetches the content of a file on a branch, and the blob SHA
+// needed to update it. Both are empty if the file doesn't exist
+func GetFileContent(path, branch string) (content, sha string, err error) {
+	file, _, response, err := client.Repositories.GetContents(ctx, Repo.Owner, Repo.Name, path, &github.RepositoryContentGetOptions{Ref: branch})
+	if response != nil && response.StatusCode == http.StatusNotFound {
+		return "", "", nil
+	} else if err != nil {
+		return "", "", err
+	} else if file == nil {
+		return "", "", fmt.Errorf("%s is not a file", path)
+	}
+	content, err = file.GetContent()
+	return content, file.GetSHA(), err
+}
+
+// CommitFile creates or updates a file on a branch, and returns the SHA of the
+// commit. The blob SHA of the existing file is needed to update it. The author
+// defaults to the owner of the access token if nil
+func CommitFile(path, branch, message, content, sha string, author *github.CommitAuthor) (string, error) {
+	opts := &github.RepositoryContentFileOptions{
+		Message: &message,
+		Content: []byte(content),
+		Branch:  &branch,
+		Author:  author,
+	}
+	var result *github.RepositoryContentResponse
+	var err error
+	if sha == "" {
+		result, _, err = client.Repositories.CreateFile(ctx, Repo.Owner, Repo.Name, path, opts)
+	} else {
+		opts.SHA = &sha
+		result, _, err = client.Repositories.UpdateFile(ctx, Repo.Owner, Repo.Name, path, opts)
+	}
+	if err != nil {
+		return "", err
+	}
+	return result.Commit.GetSHA(), nil
+}
+
+// CreateRelease creates a release in Github, as a draft if draft is true
+func CreateRelease(tagName, body string, draft bool) (*github.RepositoryRelease, error) {
+	release, _, err := client.Repositories.CreateRelease(ctx, Repo.Owner, Repo.Name, &github.RepositoryRelease{
 		TagName: &tagName,
 		Name:    &tagName,
 		Body:    &body,
+		Draft:   &draft,
 	})
-	return err
+	return release, err
 }
 
-// GetRelease fetches a release in Github by tag
+// GetRelease fetches a release in Github by tag. Releases are listed instead of
+// fetched by tag, since drafts can't be fetched by tag.
+// Returns nil if the tag has no release
 func GetRelease(tag string) (*github.RepositoryRelease, error) {
-	release, _, err := client.Repositories.GetReleaseByTag(ctx, Repo.Owner, Repo.Name, tag)
-	return release, err
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		releases, response, err := client.Repositories.ListReleases(ctx, Repo.Owner, Repo.Name, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, release := range releases {
+			if release.GetTagName() == tag {
+				return release, nil
+			}
+		}
+		if response.NextPage == 0 {
+			return nil, nil
+		}
+		opts.Page = response.NextPage
+	}
+}
+
+// PublishRelease publishes a draft release in Github
+func PublishRelease(id int64) error {
+	_, _, err := client.Repositories.EditRelease(ctx, Repo.Owner, Repo.Name, id, &github.RepositoryRelease{
+		Draft: github.Bool(false),
+	})
+	return err
+}
+
+// EditRelease updates the name and body of a release in Github, leaving its
+// assets, flags and other fields as they are
+func EditRelease(id int64, name, body string) error {
+	_, _, err := client.Repositories.EditRelease(ctx, Repo.Owner, Repo.Name, id, &github.RepositoryRelease{
+		Name: &name,
+		Body: &body,
+	})
+	return err
 }
 
 // DeleteRelease deletes a release in Github
@@ -185,6 +477,54 @@ func DeleteRelease(id int64) error {
 	return err
 }
 
+// GetReleaseAssets fetches all assets of a release in Github
+func GetReleaseAssets(id int64) ([]*github.ReleaseAsset, error) {
+	var assets []*github.ReleaseAsset
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		page, response, err := client.Repositories.ListReleaseAssets(ctx, Repo.Owner, Repo.Name, id, opts)
+		if err != nil {
+			return nil, err
+		}
+		assets = append(assets, page...)
+		if response.NextPage == 0 {
+			return assets, nil
+		}
+		opts.Page = response.NextPage
+	}
+}
+
+// DeleteReleaseAsset deletes an asset of a release in Github
+func DeleteReleaseAsset(id int64) error {
+	_, err := client.Repositories.DeleteReleaseAsset(ctx, Repo.Owner, Repo.Name, id)
+	return err
+}
+
+// UploadReleaseAsset uploads an asset to a release in Github. The content type
+// is detected from the extension of the name, or from the content if the
+// extension is unknown
+func UploadReleaseAsset(id int64, name string, content io.ReadSeeker, size int64) error {
+	contentType := mime.TypeByExtension(filepath.Ext(name))
+	if contentType == "" {
+		head := make([]byte, 512)
+		n, err := io.ReadFull(content, head)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		contentType = http.DetectContentType(head[:n])
+		if _, err := content.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+	}
+	u := fmt.Sprintf("repos/%s/%s/releases/%d/assets?name=%s", Repo.Owner, Repo.Name, id, url.QueryEscape(name))
+	req, err := client.NewUploadRequest(u, content, size, contentType)
+	if err != nil {
+		return err
+	}
+	_, err = client.Do(ctx, req, new(github.ReleaseAsset))
+	return err
+}
+
 func searchQuery(searchMap map[string]interface{}) (query string) {
 	for key, value := range searchMap {
 		query += fmt.Sprintf(" %s:%v", key, value)
@@ -9,8 +9,11 @@ require (
 	github.com/mitchellh/go-homedir v1.0.0
 	github.com/shurcooL/githubv4 v0.0.0-20181216014959-b89dc648a159
 	github.com/shurcooL/graphql v0.0.0-20181231061246-d48a9a75455f // indirect
+	github.com/shurcooL/sanitized_anchor_name v1.0.0 // indirect
 	github.com/sirupsen/logrus v1.3.0
 	github.com/spf13/cobra v0.0.3
 	github.com/spf13/viper v1.3.1
 	golang.org/x/oauth2 v0.0.0-20190115181402-5dab4167f31c
+	gopkg.in/russross/blackfriday.v2 v2.0.0
+	gopkg.in/yaml.v2 v2.2.2
 )
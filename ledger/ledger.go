@@ -0,0 +1,131 @@
+package ledger
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+)
+
+// Ledger records what was published for each release and destination, so
+// unchanged releases are not published again
+type Ledger struct {
+	Entries []Entry `json:"entries"`
+
+	store Store
+}
+
+// Store loads and saves the content of a ledger
+type Store interface {
+	Load() (string, error)
+	Save(content string) error
+}
+
+// FileStore stores a ledger in a local file
+type FileStore string
+
+// Load reads the ledger file. A missing file is an empty ledger
+func (f FileStore) Load() (string, error) {
+	content, err := ioutil.ReadFile(string(f))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	return string(content), err
+}
+
+// Save writes the ledger file
+func (f FileStore) Save(content string) error {
+	return ioutil.WriteFile(string(f), []byte(content), 0644)
+}
+
+// Open loads a ledger from a store. Publications recorded in the ledger are
+// saved to the store right away
+func Open(store Store) (*Ledger, error) {
+	content, err := store.Load()
+	if err != nil {
+		return nil, err
+	}
+	l, err := Parse(content)
+	if err != nil {
+		return nil, err
+	}
+	l.store = store
+	return l, nil
+}
+
+// Entry records the last publication of a release to a destination, like
+// 'github:owner/repo' or 'slack:#channel'. Hash is the hash of the content
+type Entry struct {
+	Tag         string    `json:"tag"`
+	Destination string    `json:"destination"`
+	Hash        string    `json:"hash"`
+	PublishedAt time.Time `json:"published_at"`
+}
+
+// Parse parses a ledger. Empty content is an empty ledger
+func Parse(content string) (*Ledger, error) {
+	l := &Ledger{}
+	if strings.TrimSpace(content) == "" {
+		return l, nil
+	}
+	if err := json.Unmarshal([]byte(content), l); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// String returns the ledger as indented JSON
+func (l *Ledger) String() string {
+	if l.Entries == nil {
+		l.Entries = []Entry{}
+	}
+	out, _ := json.MarshalIndent(l, "", "  ")
+	return string(out) + "\n"
+}
+
+// Published checks if the content with the hash was the last content published
+// for the tag to the destination
+func (l *Ledger) Published(tag, destination, hash string) bool {
+	for _, entry := range l.Entries {
+		if entry.Tag == tag && entry.Destination == destination {
+			return entry.Hash == hash
+		}
+	}
+	return false
+}
+
+// Record records that the content with the hash was published for the tag to
+// the destination, replacing the previous entry of the tag and destination.
+// Ledgers opened from a store are saved to it
+func (l *Ledger) Record(tag, destination, hash string, at time.Time) error {
+	entry := Entry{Tag: tag, Destination: destination, Hash: hash, PublishedAt: at.UTC()}
+	found := false
+	for i := range l.Entries {
+		if l.Entries[i].Tag == tag && l.Entries[i].Destination == destination {
+			l.Entries[i] = entry
+			found = true
+		}
+	}
+	if !found {
+		l.Entries = append(l.Entries, entry)
+	}
+	if l.store == nil {
+		return nil
+	}
+	return l.store.Save(l.String())
+}
+
+// Hash returns the hex encoded SHA-256 hash of the parts of some content
+func Hash(parts ...[]byte) string {
+	h := sha256.New()
+	for _, part := range parts {
+		// The length keeps the parts apart, so moving bytes between them changes the hash
+		fmt.Fprintf(h, "%d:", len(part))
+		h.Write(part)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
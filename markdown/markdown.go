@@ -0,0 +1,284 @@
+package markdown
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	bf "gopkg.in/russross/blackfriday.v2"
+)
+
+var (
+	mentionPattern   = regexp.MustCompile(`(^|[^\w` + "`" + `])(@[A-Za-z0-9][A-Za-z0-9-]*(?:/[A-Za-z0-9][A-Za-z0-9-]*)?)`)
+	blockStartEscape = regexp.MustCompile(`(?m)^([#>])`)
+	backtickRun      = regexp.MustCompile("`+")
+)
+
+// Options configures how markdown is normalized
+type Options struct {
+	// MinHeadingLevel is the highest heading level allowed. Headings are demoted
+	// so the highest heading of the markdown gets this level, but never below 6
+	MinHeadingLevel int
+	// BaseURL is used to make relative links absolute
+	BaseURL string
+	// ImageBaseURL is used to make relative images absolute. Images use
+	// BaseURL if it's empty
+	ImageBaseURL string
+	// WrapMentions wraps @mentions in code spans, so they don't notify anyone
+	WrapMentions bool
+}
+
+type normalizer struct {
+	Options
+	baseURL      *url.URL
+	imageBaseURL *url.URL
+	levelShift   int
+}
+
+// Normalize parses markdown and writes it back, so it can be embedded in
+// another markdown document without breaking its structure. Headings are
+// demoted, code fences are balanced, relative links are made absolute and
+// mentions are optionally wrapped
+func Normalize(input string, opts Options) string {
+	parser := bf.New(bf.WithExtensions(bf.CommonExtensions &^ bf.Titleblock))
+	ast := parser.Parse([]byte(strings.Replace(input, "\r\n", "\n", -1)))
+
+	n := &normalizer{Options: opts}
+	if opts.BaseURL != "" {
+		n.baseURL, _ = url.Parse(opts.BaseURL)
+	}
+	n.imageBaseURL = n.baseURL
+	if opts.ImageBaseURL != "" {
+		n.imageBaseURL, _ = url.Parse(opts.ImageBaseURL)
+	}
+	highest := 0
+	ast.Walk(func(node *bf.Node, entering bool) bf.WalkStatus {
+		if entering && node.Type == bf.Heading && (highest == 0 || node.Level < highest) {
+			highest = node.Level
+		}
+		return bf.GoToNext
+	})
+	if highest > 0 && highest < opts.MinHeadingLevel {
+		n.levelShift = opts.MinHeadingLevel - highest
+	}
+	return strings.TrimSpace(n.blocks(ast))
+}
+
+// blocks renders the block children of a node, separated by blank lines
+// unless the node is an item of a tight list
+func (n *normalizer) blocks(parent *bf.Node) string {
+	var blocks []string
+	for node := parent.FirstChild; node != nil; node = node.Next {
+		if block := n.block(node); block != "" {
+			blocks = append(blocks, block)
+		}
+	}
+	separator := "\n\n"
+	if parent.Type == bf.Item && parent.Parent != nil && parent.Parent.Tight {
+		separator = "\n"
+	}
+	return strings.Join(blocks, separator)
+}
+
+func (n *normalizer) block(node *bf.Node) string {
+	switch node.Type {
+	case bf.Paragraph:
+		return blockStartEscape.ReplaceAllString(n.inlines(node, false), `\$1`)
+	case bf.Heading:
+		level := node.Level + n.levelShift
+		if level > 6 {
+			level = 6
+		}
+		return strings.Repeat("#", level) + " " + n.inlines(node, false)
+	case bf.HorizontalRule:
+		return "---"
+	case bf.BlockQuote:
+		return prefixLines(n.blocks(node), "> ", "> ")
+	case bf.List:
+		return n.list(node)
+	case bf.CodeBlock:
+		return codeBlock(node)
+	case bf.HTMLBlock:
+		return strings.TrimSpace(string(node.Literal))
+	case bf.Table:
+		return n.table(node)
+	}
+	return n.blocks(node)
+}
+
+func (n *normalizer) list(node *bf.Node) string {
+	var items []string
+	number := 1
+	for item := node.FirstChild; item != nil; item = item.Next {
+		marker := "- "
+		if node.ListFlags&bf.ListTypeOrdered != 0 {
+			delimiter := node.Delimiter
+			if delimiter == 0 {
+				delimiter = '.'
+			}
+			marker = fmt.Sprintf("%d%c ", number, delimiter)
+			number++
+		} else if node.BulletChar != 0 {
+			marker = string(node.BulletChar) + " "
+		}
+		indent := strings.Repeat(" ", len(marker))
+		items = append(items, prefixLines(n.blocks(item), marker, indent))
+	}
+	if node.Tight {
+		return strings.Join(items, "\n")
+	}
+	return strings.Join(items, "\n\n")
+}
+
+func (n *normalizer) table(node *bf.Node) string {
+	var rows []string
+	node.Walk(func(row *bf.Node, entering bool) bf.WalkStatus {
+		if !entering || row.Type != bf.TableRow {
+			return bf.GoToNext
+		}
+		var cells, aligns []string
+		for cell := row.FirstChild; cell != nil; cell = cell.Next {
+			cells = append(cells, strings.Replace(n.inlines(cell, false), "|", `\|`, -1))
+			switch cell.Align {
+			case bf.TableAlignmentLeft:
+				aligns = append(aligns, ":---")
+			case bf.TableAlignmentRight:
+				aligns = append(aligns, "---:")
+			case bf.TableAlignmentCenter:
+				aligns = append(aligns, ":---:")
+			default:
+				aligns = append(aligns, "---")
+			}
+		}
+		rows = append(rows, "| "+strings.Join(cells, " | ")+" |")
+		if row.Parent.Type == bf.TableHead {
+			rows = append(rows, "| "+strings.Join(aligns, " | ")+" |")
+		}
+		return bf.SkipChildren
+	})
+	return strings.Join(rows, "\n")
+}
+
+// inlines renders the inline children of a node. Mentions inside links are
+// never wrapped, since they're part of the link text
+func (n *normalizer) inlines(parent *bf.Node, inLink bool) string {
+	buf := new(bytes.Buffer)
+	for node := parent.FirstChild; node != nil; node = node.Next {
+		switch node.Type {
+		case bf.Text:
+			buf.WriteString(n.text(string(node.Literal), inLink))
+		case bf.Emph:
+			buf.WriteString("*" + n.inlines(node, inLink) + "*")
+		case bf.Strong:
+			buf.WriteString("**" + n.inlines(node, inLink) + "**")
+		case bf.Del:
+			buf.WriteString("~~" + n.inlines(node, inLink) + "~~")
+		case bf.Code:
+			buf.WriteString(codeSpan(string(node.Literal)))
+		case bf.Link:
+			buf.WriteString(fmt.Sprintf("[%s](%s%s)", n.inlines(node, true), absolute(n.baseURL, node.Destination), linkTitle(node.Title)))
+		case bf.Image:
+			buf.WriteString(fmt.Sprintf("![%s](%s%s)", n.inlines(node, true), absolute(n.imageBaseURL, node.Destination), linkTitle(node.Title)))
+		case bf.Softbreak:
+			buf.WriteString("\n")
+		case bf.Hardbreak:
+			buf.WriteString("\\\n")
+		case bf.HTMLSpan:
+			buf.Write(node.Literal)
+		default:
+			buf.WriteString(n.inlines(node, inLink))
+		}
+	}
+	return buf.String()
+}
+
+func (n *normalizer) text(s string, inLink bool) string {
+	s = escapeText(s)
+	if n.WrapMentions && !inLink {
+		s = mentionPattern.ReplaceAllString(s, "$1`$2`")
+	}
+	return s
+}
+
+// absolute resolves a link destination against a base URL. Anchors and links
+// that are already absolute are left as they are
+func absolute(base *url.URL, destination []byte) string {
+	dest := string(destination)
+	link, err := url.Parse(dest)
+	if base == nil || err != nil || link.IsAbs() || link.Host != "" || strings.HasPrefix(dest, "#") || dest == "" {
+		return dest
+	}
+	return base.ResolveReference(link).String()
+}
+
+// escapeText escapes the characters that would start inline markdown
+func escapeText(s string) string {
+	buf := new(bytes.Buffer)
+	for i, r := range s {
+		switch r {
+		case '\\', '`', '*':
+			buf.WriteRune('\\')
+		case '_':
+			if i == 0 || i == len(s)-1 || !isWordByte(s[i-1]) || !isWordByte(s[i+1]) {
+				buf.WriteRune('\\')
+			}
+		}
+		buf.WriteRune(r)
+	}
+	return buf.String()
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+}
+
+// codeBlock renders a code block with a fence longer than any backtick run in it
+func codeBlock(node *bf.Node) string {
+	code := strings.TrimRight(string(node.Literal), "\n")
+	fence := strings.Repeat("`", longestRun(code, 2)+1)
+	return fmt.Sprintf("%s%s\n%s\n%s", fence, strings.TrimSpace(string(node.Info)), code, fence)
+}
+
+// codeSpan renders inline code with more backticks than any backtick run in it
+func codeSpan(code string) string {
+	fence := strings.Repeat("`", longestRun(code, 0)+1)
+	if strings.HasPrefix(code, "`") || strings.HasSuffix(code, "`") {
+		code = " " + code + " "
+	}
+	return fence + code + fence
+}
+
+func longestRun(s string, min int) int {
+	longest := min
+	for _, run := range backtickRun.FindAllString(s, -1) {
+		if len(run) > longest {
+			longest = len(run)
+		}
+	}
+	return longest
+}
+
+func linkTitle(title []byte) string {
+	if len(title) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(` "%s"`, strings.Replace(string(title), `"`, `\"`, -1))
+}
+
+// prefixLines prefixes the first line of s with first, and the other non-empty lines with rest
+func prefixLines(s, first, rest string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		prefix := rest
+		if i == 0 {
+			prefix = first
+		}
+		if line == "" {
+			prefix = strings.TrimRight(prefix, " ")
+		}
+		lines[i] = prefix + line
+	}
+	return strings.Join(lines, "\n")
+}
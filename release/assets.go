@@ -0,0 +1,102 @@
+package release
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/franzwilhelm/gitflow-release-notes/githubutil"
+	"github.com/google/go-github/github"
+	"github.com/sirupsen/logrus"
+)
+
+// assetFiles returns the files matching the asset patterns of the options
+func assetFiles(opts PushOptions) ([]string, error) {
+	var filenames []string
+	for _, pattern := range opts.Assets {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		} else if len(matches) == 0 {
+			return nil, fmt.Errorf("no files match asset %s", pattern)
+		}
+		filenames = append(filenames, matches...)
+	}
+	return filenames, nil
+}
+
+// uploadAssets attaches the release notes in the formats of the options, and
+// the files, to a release in Github. Existing assets with the same name are
+// replaced
+func (r *Release) uploadAssets(id int64, formats, filenames []string) error {
+	if len(formats) == 0 && len(filenames) == 0 {
+		return nil
+	}
+	// Releases that would be created in dry runs have no ID nor assets
+	var existing []*github.ReleaseAsset
+	if id != 0 {
+		var err error
+		if existing, err = githubutil.GetReleaseAssets(id); err != nil {
+			return fmt.Errorf("could not fetch release assets: %v", err)
+		}
+	}
+	for _, format := range formats {
+		ext, err := FormatExtension(format)
+		if err != nil {
+			return err
+		}
+		buf := new(bytes.Buffer)
+		if err := r.Generate(buf, format); err != nil {
+			return err
+		}
+		if err := uploadAsset(id, existing, r.Filename(ext), bytes.NewReader(buf.Bytes()), int64(buf.Len())); err != nil {
+			return err
+		}
+	}
+	for _, filename := range filenames {
+		if err := uploadFile(id, existing, filename); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func uploadFile(id int64, existing []*github.ReleaseAsset, filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	return uploadAsset(id, existing, filepath.Base(filename), f, info.Size())
+}
+
+// uploadAsset uploads an asset to a release in Github, after deleting the
+// existing asset with the same name
+func uploadAsset(id int64, existing []*github.ReleaseAsset, name string, content io.ReadSeeker, size int64) error {
+	for _, asset := range existing {
+		if asset.GetName() == name && DryRun {
+			DryRunf("would replace asset %s (%d bytes)", name, size)
+			return nil
+		} else if asset.GetName() == name {
+			if err := githubutil.DeleteReleaseAsset(asset.GetID()); err != nil {
+				return fmt.Errorf("could not replace asset %s: %v", name, err)
+			}
+			logrus.Infof("Replacing asset %s", name)
+		}
+	}
+	if DryRun {
+		DryRunf("would upload asset %s (%d bytes)", name, size)
+		return nil
+	}
+	if err := githubutil.UploadReleaseAsset(id, name, content, size); err != nil {
+		return fmt.Errorf("could not upload asset %s: %v", name, err)
+	}
+	logrus.Infof("Uploaded asset %s", name)
+	return nil
+}
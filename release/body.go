@@ -0,0 +1,190 @@
+package release
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/franzwilhelm/gitflow-release-notes/markdown"
+	"github.com/google/go-github/github"
+)
+
+const (
+	// FallbackFull shows the whole body when no release note block is found
+	FallbackFull = "full"
+	// FallbackTitle shows only the title when no release note block is found
+	FallbackTitle = "title"
+	// FallbackParagraph shows the first paragraph of the body, truncated to
+	// the max length, when no release note block is found
+	FallbackParagraph = "paragraph"
+)
+
+var (
+	fencePattern         = regexp.MustCompile("^\\s*(```|~~~)")
+	headingPattern       = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	htmlCommentPattern   = regexp.MustCompile(`(?s)<!--.*?-->`)
+	uncheckedTaskPattern = regexp.MustCompile(`(?m)^[ \t]*[-*+][ \t]+\[ \][^\n]*\n?`)
+	blankLinesPattern    = regexp.MustCompile(`\n{3,}`)
+)
+
+// BodyExtraction configures which part of pull request bodies is shown in the
+// release notes. A block between '<!-- marker -->' and '<!-- /marker -->' comments
+// is used first, then the content below a heading with the heading title.
+// If neither is found, the fallback decides what to show
+type BodyExtraction struct {
+	Heading   string `mapstructure:"heading"`
+	Marker    string `mapstructure:"marker"`
+	Fallback  string `mapstructure:"fallback"`
+	MaxLength int    `mapstructure:"max_length"`
+
+	headingRegexp *regexp.Regexp
+}
+
+var bodyExtraction = BodyExtraction{Fallback: FallbackFull}
+
+// UseBodyExtraction validates the body extraction and uses it for the pull
+// request bodies of all releases
+func UseBodyExtraction(extraction BodyExtraction) error {
+	switch extraction.Fallback {
+	case "":
+		extraction.Fallback = FallbackFull
+	case FallbackFull, FallbackTitle, FallbackParagraph:
+	default:
+		return fmt.Errorf("invalid body fallback %s. Use %s, %s or %s", extraction.Fallback, FallbackFull, FallbackTitle, FallbackParagraph)
+	}
+	if extraction.MaxLength <= 0 {
+		extraction.MaxLength = 280
+	}
+	if extraction.Heading != "" {
+		extraction.headingRegexp = regexp.MustCompile(`(?i)^` + regexp.QuoteMeta(extraction.Heading) + `\s*:?$`)
+	}
+	bodyExtraction = extraction
+	return nil
+}
+
+// WrapMentions wraps @mentions in pull request bodies in code spans, so
+// publishing the release notes doesn't notify the mentioned users
+var WrapMentions bool
+
+// DisplayBody returns the body shown for a pull request in the release notes.
+// The release note block is extracted from the body, and migration notes are
+// moved to the end of it, below a bold title. The markdown is normalized so
+// it can't break the structure of the release notes. Releases created from a
+// document use its bodies as they are
+func (r *Release) DisplayBody(pr github.PullRequest) string {
+	if docPR, ok := r.documentPullRequest(pr); ok {
+		return docPR.Body
+	}
+	notes, rest, _ := cutHeadingSection(pr.GetBody(), migrationHeading)
+	body := extractReleaseNote(rest)
+	if notes != "" {
+		body = strings.TrimSpace(fmt.Sprintf("%s\n\n**Migration:**\n%s", body, cleanBody(notes)))
+	}
+	return markdown.Normalize(body, markdown.Options{
+		MinHeadingLevel: 5, // Below the '####' heading of each pull request
+		BaseURL:         r.blobURL(),
+		ImageBaseURL:    r.rawURL(),
+		WrapMentions:    WrapMentions,
+	})
+}
+
+// blobURL returns the Github URL relative links in the release are resolved against
+func (r *Release) blobURL() string {
+	return fmt.Sprintf("https://www.github.com/%s/blob/%s/", r.Repository.Full(), r.fileRef())
+}
+
+// rawURL returns the Github URL relative images in the release are resolved
+// against. Blob URLs are HTML pages, so images need the raw file instead
+func (r *Release) rawURL() string {
+	return fmt.Sprintf("https://github.com/%s/raw/%s/", r.Repository.Full(), r.fileRef())
+}
+
+// fileRef returns the ref the files of the repository are linked at
+func (r *Release) fileRef() string {
+	if r.IsUnreleased() {
+		return r.Head
+	} else if r.TagName() == "" {
+		return r.Base
+	}
+	return r.TagName()
+}
+
+// extractReleaseNote extracts the release note block of a body using the body
+// extraction, and cleans it
+func extractReleaseNote(body string) string {
+	e := bodyExtraction
+	if e.Marker != "" {
+		if block, ok := markedBlock(body, e.Marker); ok {
+			return cleanBody(block)
+		}
+	}
+	if e.headingRegexp != nil {
+		if content, _, ok := cutHeadingSection(body, e.headingRegexp); ok {
+			return cleanBody(content)
+		}
+	}
+	switch e.Fallback {
+	case FallbackTitle:
+		return ""
+	case FallbackParagraph:
+		paragraph := strings.SplitN(cleanBody(body), "\n\n", 2)[0]
+		if runes := []rune(paragraph); len(runes) > e.MaxLength {
+			paragraph = strings.TrimSpace(string(runes[:e.MaxLength])) + "…"
+		}
+		return paragraph
+	}
+	return cleanBody(body)
+}
+
+// markedBlock returns the content after a '<!-- marker -->' comment until a
+// '<!-- /marker -->' comment, another '<!-- marker -->' comment or the end
+func markedBlock(body, marker string) (string, bool) {
+	start := regexp.MustCompile(`<!--\s*` + regexp.QuoteMeta(marker) + `\s*-->`)
+	end := regexp.MustCompile(`<!--\s*/?` + regexp.QuoteMeta(marker) + `\s*-->`)
+	loc := start.FindStringIndex(body)
+	if loc == nil {
+		return "", false
+	}
+	block := body[loc[1]:]
+	if endLoc := end.FindStringIndex(block); endLoc != nil {
+		block = block[:endLoc[0]]
+	}
+	return block, true
+}
+
+// cleanBody removes HTML comments and unchecked task list items from a body
+func cleanBody(body string) string {
+	body = htmlCommentPattern.ReplaceAllString(body, "")
+	body = uncheckedTaskPattern.ReplaceAllString(body, "")
+	body = blankLinesPattern.ReplaceAllString(strings.Replace(body, "\r\n", "\n", -1), "\n\n")
+	return strings.TrimSpace(body)
+}
+
+// cutHeadingSection finds the first markdown heading with a title matching the
+// pattern. It returns the content below the heading until the next heading of
+// the same or a higher level, and the body with the heading and content cut out.
+// Headings inside code fences are ignored
+func cutHeadingSection(body string, title *regexp.Regexp) (content, rest string, found bool) {
+	lines := strings.Split(strings.Replace(body, "\r\n", "\n", -1), "\n")
+	var contentLines, restLines []string
+	inFence, inSection := false, false
+	level := 0
+	for _, line := range lines {
+		if fencePattern.MatchString(line) {
+			inFence = !inFence
+		} else if match := headingPattern.FindStringSubmatch(line); match != nil && !inFence {
+			if inSection && len(match[1]) <= level {
+				inSection = false
+			} else if !found && title.MatchString(match[2]) {
+				found, inSection, level = true, true, len(match[1])
+				continue
+			}
+		}
+		if inSection {
+			contentLines = append(contentLines, line)
+		} else {
+			restLines = append(restLines, line)
+		}
+	}
+	return strings.TrimSpace(strings.Join(contentLines, "\n")), strings.TrimSpace(strings.Join(restLines, "\n")), found
+}
@@ -0,0 +1,35 @@
+package release
+
+import (
+	"regexp"
+
+	"github.com/google/go-github/github"
+)
+
+// BreakingChanges is the title of the section listing breaking changes first
+const BreakingChanges = "⚠ Breaking changes"
+
+const breakingChangesColor = "#ff9f1c"
+
+var (
+	// BreakingLabels are the pull request labels marking a breaking change
+	BreakingLabels   = []string{"breaking", "breaking change", "breaking-change"}
+	breakingHeading  = regexp.MustCompile(`(?mi)^#{1,6}\s*breaking changes?\s*:?\s*$`)
+	breakingFooter   = regexp.MustCompile(`(?m)^BREAKING[ -]CHANGE:`)
+	migrationHeading = regexp.MustCompile(`(?i)^migration`)
+)
+
+// IsBreaking checks if a pull request is labeled as breaking, has a
+// 'BREAKING CHANGE:' footer line in its body, or a breaking changes heading
+func IsBreaking(pr github.PullRequest) bool {
+	return hasLabel(pr, BreakingLabels) ||
+		breakingFooter.MatchString(pr.GetBody()) ||
+		breakingHeading.MatchString(pr.GetBody())
+}
+
+// isBreaking checks if a pull request is breaking by label, body or a
+// Conventional Commits title with an exclamation mark
+func (r *Release) isBreaking(pr github.PullRequest) bool {
+	message, _ := r.ConventionalMessage(pr)
+	return message.Breaking || IsBreaking(pr)
+}
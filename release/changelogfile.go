@@ -0,0 +1,191 @@
+package release
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/franzwilhelm/gitflow-release-notes/changelog"
+	"github.com/franzwilhelm/gitflow-release-notes/diff"
+	"github.com/franzwilhelm/gitflow-release-notes/markdown"
+	"github.com/sirupsen/logrus"
+)
+
+var atxHeading = regexp.MustCompile(`^(#{1,6})\s`)
+
+// UpdateChangelogFile inserts or replaces the sections of the releases in a
+// Keep a Changelog style file, which is created if it doesn't exist. Releases
+// are inserted in version order, and the compare links in the footer are updated.
+// Sections of other releases are left as they are, so rerunning changes nothing.
+// Dry runs print the diff of the file instead of writing it
+func UpdateChangelogFile(filename string, releases []Release) error {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	updated, err := UpdateChangelogContent(string(content), releases)
+	if err != nil {
+		return err
+	}
+	if DryRun {
+		if updated != string(content) {
+			DryRunf("would update %s:\n%s", filename, diff.Unified(string(content), updated, filename, filename))
+		}
+		return nil
+	}
+	return ioutil.WriteFile(filename, []byte(updated), 0644)
+}
+
+// UpdateChangelogContent inserts or replaces the sections of the releases in
+// the content of a Keep a Changelog style file, and returns the updated content
+func UpdateChangelogContent(content string, releases []Release) (string, error) {
+	c := changelog.Parse(content)
+	if err := UpdateChangelog(c, releases); err != nil {
+		return "", err
+	}
+	return c.String(), nil
+}
+
+// UpdateChangelog inserts or replaces the sections of the releases in the
+// changelog. Inserting a new latest release removes the pull requests it
+// contains from the unreleased section, since they are released now
+func UpdateChangelog(c *changelog.Changelog, releases []Release) error {
+	if _, ok := c.Section(changelog.Unreleased); !ok {
+		c.Set(changelog.Section{Name: changelog.Unreleased})
+	}
+	for i := range releases {
+		r := &releases[i]
+		section, err := r.changelogSection()
+		if err != nil {
+			return err
+		}
+		_, exists := c.Section(section.Name)
+		c.Set(section)
+		c.SetLink(section.Name, r.changelogURL())
+		if r.IsUnreleased() {
+			continue
+		}
+		// Unreleased changes are compared with the latest release, unless they were generated
+		if latest := c.Latest(); latest != nil && latest.Is(section) && !hasUnreleased(releases) {
+			c.SetLink(changelog.Unreleased, fmt.Sprintf("https://www.github.com/%s/compare/%s...HEAD", r.Repository.Full(), r.TagName()))
+			// The generated unreleased changes are part of a new latest release now
+			if !exists {
+				unreleased, _ := c.Section(changelog.Unreleased)
+				c.Set(changelog.Section{Name: changelog.Unreleased, Body: r.removeReleasedEntries(unreleased.Body)})
+			}
+		}
+	}
+	return nil
+}
+
+// removeReleasedEntries removes the pull requests of the release from the body
+// of the unreleased section, along with the headings left without any entries.
+// A pull request entry is a heading linking to it, and ends at the next heading
+// of the same or a higher level. Hand-written text is kept
+func (r *Release) removeReleasedEntries(body string) string {
+	type block struct {
+		level int // The heading level, or 0 for text before the first heading
+		lines []string
+		empty bool
+	}
+	var blocks []*block
+	current := &block{empty: true}
+	fenced := false
+	for _, line := range strings.Split(body, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fenced = !fenced
+		}
+		if match := atxHeading.FindStringSubmatch(line); match != nil && !fenced {
+			blocks = append(blocks, current)
+			current = &block{level: len(match[1]), lines: []string{line}, empty: true}
+			continue
+		}
+		current.lines = append(current.lines, line)
+		current.empty = current.empty && trimmed == ""
+	}
+	blocks = append(blocks, current)
+
+	released := func(heading string) bool {
+		for _, pr := range r.PullRequests {
+			if strings.Contains(heading, fmt.Sprintf("[#%d]", pr.GetNumber())) || pr.GetHTMLURL() != "" && strings.Contains(heading, "("+pr.GetHTMLURL()+")") {
+				return true
+			}
+		}
+		return false
+	}
+	var kept []*block
+	for i := 0; i < len(blocks); i++ {
+		b := blocks[i]
+		if b.level == 0 || !released(b.lines[0]) {
+			kept = append(kept, b)
+			continue
+		}
+		for i+1 < len(blocks) && blocks[i+1].level > b.level {
+			i++
+		}
+	}
+	// Headings without entries are dropped from the end, so a section heading
+	// is dropped too when all of its scopes are
+	var lines []string
+	nextLevel := 0
+	for i := len(kept) - 1; i >= 0; i-- {
+		b := kept[i]
+		if b.level > 0 && b.empty && (nextLevel == 0 || nextLevel <= b.level) {
+			continue
+		}
+		lines = append(b.lines, lines...)
+		if b.level > 0 {
+			nextLevel = b.level
+		}
+	}
+	remaining := strings.TrimSpace(strings.Join(lines, "\n"))
+	if remaining != "" {
+		logrus.Infof("Kept the text in the %s section that isn't part of %s", changelog.Unreleased, r.Title())
+	}
+	return remaining
+}
+
+func hasUnreleased(releases []Release) bool {
+	for _, r := range releases {
+		if r.IsUnreleased() {
+			return true
+		}
+	}
+	return false
+}
+
+// changelogSection returns the changelog section of the release. The headings
+// of the markdown are demoted below the section heading
+func (r *Release) changelogSection() (changelog.Section, error) {
+	section := changelog.Section{Name: changelog.Unreleased}
+	if r.Window != nil {
+		return section, fmt.Errorf("can't add the release %s to a changelog, since it has no tag", r.Title())
+	} else if !r.IsUnreleased() {
+		section.Name = r.TagName()
+		if r.Tag.Version != nil {
+			section.Name = r.Tag.Version.String()
+		}
+		if date := r.Date(); date != nil {
+			section.Date = date.Format("2006-01-02")
+		}
+	}
+	buf := new(bytes.Buffer)
+	if err := r.GenerateMarkdownChangelog(buf); err != nil {
+		return section, err
+	}
+	section.Body = markdown.Normalize(buf.String(), markdown.Options{MinHeadingLevel: 3})
+	return section, nil
+}
+
+// changelogURL returns the compare URL of the release, or the release page if
+// it has no base
+func (r *Release) changelogURL() string {
+	if url := r.CompareURL(); url != "" {
+		return url
+	}
+	return r.GithubURL()
+}
@@ -0,0 +1,43 @@
+package release
+
+import (
+	"github.com/franzwilhelm/gitflow-release-notes/conventional"
+	"github.com/franzwilhelm/gitflow-release-notes/gitflow"
+	"github.com/google/go-github/github"
+)
+
+// ConventionalCommits enables parsing of Conventional Commits pull request titles
+// and squash commit messages. Their types are matched by the section rules,
+// scopes group pull requests within sections and descriptions are used as titles
+var ConventionalCommits bool
+
+// ConventionalMessage parses the title of a pull request, or the message of its
+// merge commit if the title doesn't follow Conventional Commits.
+// Returns false if neither does, or if Conventional Commits is disabled
+func (r *Release) ConventionalMessage(pr github.PullRequest) (conventional.Message, bool) {
+	if !ConventionalCommits {
+		return conventional.Message{}, false
+	}
+	if message, ok := conventional.Parse(pr.GetTitle()); ok {
+		return message, true
+	}
+	for _, commit := range r.Commits {
+		if commit.GetSHA() == pr.GetMergeCommitSHA() {
+			return conventional.Parse(commit.GetCommit().GetMessage())
+		}
+	}
+	return conventional.Message{}, false
+}
+
+// DisplayTitle returns the title shown for a pull request in the release notes.
+// This is the description of Conventional Commits titles, or the title with
+// GitFlow prefixes removed. Releases created from a document use its clean titles
+func (r *Release) DisplayTitle(pr github.PullRequest) string {
+	if docPR, ok := r.documentPullRequest(pr); ok {
+		return docPR.CleanTitle
+	}
+	if message, ok := r.ConventionalMessage(pr); ok {
+		return message.Title()
+	}
+	return gitflow.RemovePrefixes(pr.GetTitle())
+}
@@ -0,0 +1,256 @@
+package release
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/go-github/github"
+	version "github.com/hashicorp/go-version"
+)
+
+// SchemaVersion is the version of the release document schema, published in
+// schema/release-v1.schema.json. It's increased on breaking changes only, so
+// fields may be added to documents without changing it
+const SchemaVersion = 1
+
+// Document is the structured form of a release, used for JSON and YAML output.
+// Titles and bodies are the ones shown in the release notes, so a document
+// contains everything needed to render them
+type Document struct {
+	SchemaVersion int               `json:"schema_version" yaml:"schema_version"`
+	Repository    string            `json:"repository" yaml:"repository"`
+	Title         string            `json:"title" yaml:"title"`
+	Tag           string            `json:"tag,omitempty" yaml:"tag,omitempty"`
+	Version       string            `json:"version,omitempty" yaml:"version,omitempty"`
+	Date          *time.Time        `json:"date,omitempty" yaml:"date,omitempty"`
+	PreviousTag   string            `json:"previous_tag,omitempty" yaml:"previous_tag,omitempty"`
+	Base          string            `json:"base,omitempty" yaml:"base,omitempty"`
+	Head          string            `json:"head,omitempty" yaml:"head,omitempty"`
+	Window        *TimeWindow       `json:"window,omitempty" yaml:"window,omitempty"`
+	URL           string            `json:"url" yaml:"url"`
+	CompareURL    string            `json:"compare_url,omitempty" yaml:"compare_url,omitempty"`
+	Sections      []DocumentSection `json:"sections" yaml:"sections"`
+	Commits       []DocumentCommit  `json:"commits" yaml:"commits"`
+}
+
+// DocumentSection is a section of the release notes. Its pull requests are
+// ordered like in the notes, with the pull requests of each scope together
+type DocumentSection struct {
+	Title        string                `json:"title" yaml:"title"`
+	Color        string                `json:"color,omitempty" yaml:"color,omitempty"`
+	Bump         Bump                  `json:"bump,omitempty" yaml:"bump,omitempty"`
+	PullRequests []DocumentPullRequest `json:"pull_requests" yaml:"pull_requests"`
+}
+
+// DocumentPullRequest is a pull request in a section of the release notes.
+// Title is the original pull request title, and CleanTitle the one shown
+type DocumentPullRequest struct {
+	Number         int        `json:"number" yaml:"number"`
+	Title          string     `json:"title" yaml:"title"`
+	CleanTitle     string     `json:"clean_title" yaml:"clean_title"`
+	Body           string     `json:"body" yaml:"body"`
+	Author         string     `json:"author" yaml:"author"`
+	Labels         []string   `json:"labels" yaml:"labels"`
+	URL            string     `json:"url" yaml:"url"`
+	Scope          string     `json:"scope,omitempty" yaml:"scope,omitempty"`
+	Breaking       bool       `json:"breaking,omitempty" yaml:"breaking,omitempty"`
+	Branch         string     `json:"branch,omitempty" yaml:"branch,omitempty"`
+	MergeCommitSHA string     `json:"merge_commit_sha,omitempty" yaml:"merge_commit_sha,omitempty"`
+	MergedAt       *time.Time `json:"merged_at,omitempty" yaml:"merged_at,omitempty"`
+}
+
+// DocumentCommit is a commit of the release
+type DocumentCommit struct {
+	SHA     string     `json:"sha" yaml:"sha"`
+	Message string     `json:"message" yaml:"message"`
+	Author  string     `json:"author" yaml:"author"`
+	Date    *time.Time `json:"date,omitempty" yaml:"date,omitempty"`
+	URL     string     `json:"url" yaml:"url"`
+}
+
+// Date returns the date of the release. This is the date of its newest commit,
+// or the end of the time window for releases made from one
+func (r *Release) Date() *time.Time {
+	if r.Window != nil {
+		return &r.Window.Until
+	}
+	var date *time.Time
+	for _, commit := range r.Commits {
+		committed := commit.GetCommit().GetCommitter().GetDate()
+		if !committed.IsZero() && (date == nil || committed.After(*date)) {
+			date = &committed
+		}
+	}
+	return date
+}
+
+// Document returns the structured form of the release. Releases created from
+// a document return it as it is
+func (r *Release) Document() Document {
+	if r.document != nil {
+		return *r.document
+	}
+	doc := Document{
+		SchemaVersion: SchemaVersion,
+		Repository:    r.Repository.Full(),
+		Title:         r.Title(),
+		Tag:           r.TagName(),
+		Date:          r.Date(),
+		Head:          r.Head,
+		Window:        r.Window,
+		URL:           r.GithubURL(),
+		CompareURL:    r.CompareURL(),
+		Sections:      []DocumentSection{},
+		Commits:       []DocumentCommit{},
+	}
+	if r.Tag.Version != nil {
+		doc.Version = r.Tag.Version.String()
+	}
+	if r.Window == nil {
+		doc.PreviousTag = r.Base
+	} else {
+		doc.Base = r.Base
+	}
+	for _, section := range r.GetPullRequestSections() {
+		docSection := DocumentSection{Title: section.Title, Color: section.Color, Bump: section.Bump}
+		for _, scope := range section.Scopes {
+			for _, pr := range scope.PullRequests {
+				docSection.PullRequests = append(docSection.PullRequests, r.newDocumentPullRequest(pr, scope.Name))
+			}
+		}
+		doc.Sections = append(doc.Sections, docSection)
+	}
+	for _, commit := range r.Commits {
+		docCommit := DocumentCommit{
+			SHA:     commit.GetSHA(),
+			Message: commit.GetCommit().GetMessage(),
+			Author:  commit.GetAuthor().GetLogin(),
+			URL:     commit.GetHTMLURL(),
+		}
+		if docCommit.Author == "" {
+			docCommit.Author = commit.GetCommit().GetAuthor().GetName()
+		}
+		if date := commit.GetCommit().GetCommitter().GetDate(); !date.IsZero() {
+			docCommit.Date = &date
+		}
+		doc.Commits = append(doc.Commits, docCommit)
+	}
+	return doc
+}
+
+func (r *Release) newDocumentPullRequest(pr github.PullRequest, scope string) DocumentPullRequest {
+	message, _ := r.ConventionalMessage(pr)
+	docPR := DocumentPullRequest{
+		Number:         pr.GetNumber(),
+		Title:          pr.GetTitle(),
+		CleanTitle:     r.DisplayTitle(pr),
+		Body:           r.DisplayBody(pr),
+		Author:         pr.GetUser().GetLogin(),
+		Labels:         []string{},
+		URL:            pr.GetHTMLURL(),
+		Scope:          scope,
+		Breaking:       message.Breaking || IsBreaking(pr),
+		Branch:         pr.GetHead().GetRef(),
+		MergeCommitSHA: pr.GetMergeCommitSHA(),
+		MergedAt:       pr.MergedAt,
+	}
+	for _, label := range pr.Labels {
+		docPR.Labels = append(docPR.Labels, label.GetName())
+	}
+	return docPR
+}
+
+// FromDocument creates a release from its structured form, for instance one that
+// was saved with --format json and edited by hand. The sections, titles and
+// bodies of the document are used as they are when the release is rendered
+func FromDocument(doc Document) (*Release, error) {
+	if doc.SchemaVersion != SchemaVersion {
+		return nil, fmt.Errorf("unsupported schema version %v of release %s. Expected %v", doc.SchemaVersion, doc.Title, SchemaVersion)
+	}
+	r := &Release{Base: doc.PreviousTag, Head: doc.Head, Window: doc.Window, document: &doc}
+	if doc.Window != nil {
+		r.Base = doc.Base
+	}
+	if err := r.Repository.Set(doc.Repository); err != nil {
+		return nil, err
+	}
+	r.Tag.Data.Name = doc.Tag
+	if doc.Version != "" {
+		v, err := version.NewVersion(doc.Version)
+		if err != nil {
+			return nil, fmt.Errorf("release %s has an invalid version: %v", doc.Title, err)
+		}
+		r.Tag.Version = v
+	}
+	for _, section := range doc.Sections {
+		for _, docPR := range section.PullRequests {
+			r.PullRequests = append(r.PullRequests, docPR.pullRequest())
+		}
+	}
+	for _, docCommit := range doc.Commits {
+		r.Commits = append(r.Commits, docCommit.repositoryCommit())
+	}
+	return r, nil
+}
+
+// documentSections returns the sections of the document the release was created from
+func (r *Release) documentSections() []Section {
+	var sections []Section
+	for _, docSection := range r.document.Sections {
+		section := Section{Title: docSection.Title, Color: docSection.Color, Bump: docSection.Bump}
+		for _, docPR := range docSection.PullRequests {
+			section.add(docPR.pullRequest(), docPR.Scope)
+		}
+		if section.PullRequests != nil {
+			sections = append(sections, section)
+		}
+	}
+	return sections
+}
+
+// documentPullRequest finds a pull request in the document the release was created from
+func (r *Release) documentPullRequest(pr github.PullRequest) (DocumentPullRequest, bool) {
+	if r.document == nil {
+		return DocumentPullRequest{}, false
+	}
+	for _, section := range r.document.Sections {
+		for _, docPR := range section.PullRequests {
+			if docPR.Number == pr.GetNumber() {
+				return docPR, true
+			}
+		}
+	}
+	return DocumentPullRequest{}, false
+}
+
+func (p *DocumentPullRequest) pullRequest() github.PullRequest {
+	pr := github.PullRequest{
+		Number:         github.Int(p.Number),
+		Title:          github.String(p.Title),
+		Body:           github.String(p.Body),
+		User:           &github.User{Login: github.String(p.Author)},
+		HTMLURL:        github.String(p.URL),
+		Head:           &github.PullRequestBranch{Ref: github.String(p.Branch)},
+		MergeCommitSHA: github.String(p.MergeCommitSHA),
+		MergedAt:       p.MergedAt,
+	}
+	for _, label := range p.Labels {
+		pr.Labels = append(pr.Labels, &github.Label{Name: github.String(label)})
+	}
+	return pr
+}
+
+func (c *DocumentCommit) repositoryCommit() github.RepositoryCommit {
+	commit := github.RepositoryCommit{
+		SHA:     github.String(c.SHA),
+		HTMLURL: github.String(c.URL),
+		Commit: &github.Commit{
+			Message: github.String(c.Message),
+			Author:  &github.CommitAuthor{Name: github.String(c.Author)},
+		},
+	}
+	if c.Date != nil {
+		commit.Commit.Committer = &github.CommitAuthor{Date: c.Date}
+	}
+	return commit
+}
@@ -0,0 +1,26 @@
+package release
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// DryRun prints the changes that would be made in Github and Slack, instead of
+// making them
+var DryRun bool
+
+var dryRunChanges int
+
+// DryRunf prints a change that would be made in a dry run. The message is
+// prefixed with 'Dry run: ' and printed to stderr, so it doesn't mix with the
+// generated release notes on stdout
+func DryRunf(format string, args ...interface{}) {
+	dryRunChanges++
+	fmt.Fprintln(os.Stderr, "Dry run: "+strings.TrimRight(fmt.Sprintf(format, args...), "\n"))
+}
+
+// WouldChange checks if a dry run printed any change
+func WouldChange() bool {
+	return dryRunChanges > 0
+}
@@ -0,0 +1,66 @@
+package release
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// The formats releases can be generated in
+const (
+	Markdown  = "markdown"
+	HTML      = "html"
+	JSON      = "json"
+	YAML      = "yaml"
+	SlackJSON = "slack-json"
+)
+
+var formatExtensions = map[string]string{
+	Markdown:  "md",
+	HTML:      "html",
+	JSON:      "json",
+	YAML:      "yaml",
+	SlackJSON: "slack.json",
+}
+
+// FormatExtension returns the file extension of a format, or an error if the
+// format is not supported
+func FormatExtension(format string) (string, error) {
+	if ext, ok := formatExtensions[format]; ok {
+		return ext, nil
+	}
+	return "", fmt.Errorf("unsupported format %s. Use %s, %s, %s, %s or %s", format, Markdown, HTML, JSON, YAML, SlackJSON)
+}
+
+// Generate writes the release to the provided writer in the format. The slack
+// format is the webhook payload, addressed to the default channel of the webhook
+func (r *Release) Generate(w io.Writer, format string) error {
+	switch format {
+	case Markdown:
+		return r.GenerateMarkdownChangelog(w)
+	case HTML:
+		return r.GenerateHTMLPage(w)
+	case JSON:
+		return writeJSON(w, r.Document())
+	case SlackJSON:
+		return writeJSON(w, r.SlackMessage("", ""))
+	case YAML:
+		out, err := yaml.Marshal(r.Document())
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(out)
+		return err
+	}
+	_, err := FormatExtension(format)
+	return err
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
@@ -0,0 +1,45 @@
+package release
+
+import "strings"
+
+// The comments marking the generated notes in the body of Github releases
+const (
+	generatedStart = "<!-- generated:start -->"
+	generatedEnd   = "<!-- generated:end -->"
+)
+
+// releaseBody returns the body of a Github release with the generated notes.
+// The notes are wrapped in marker comments, and only the marked block of the
+// existing body is replaced, so hand-written text around it is kept. Existing
+// bodies without the markers are replaced entirely, and kept is false if they
+// had text other than the notes, which would be lost
+func releaseBody(existing, notes string) (body string, kept bool) {
+	block := generatedStart + "\n" + strings.TrimSpace(notes) + "\n" + generatedEnd
+	// Bodies edited in Github have Windows line endings
+	existing = strings.Replace(existing, "\r\n", "\n", -1)
+	start := strings.Index(existing, generatedStart)
+	end := -1
+	if start >= 0 {
+		end = strings.Index(existing[start:], generatedEnd)
+	}
+	if end < 0 {
+		trimmed := strings.TrimSpace(existing)
+		return block, trimmed == "" || trimmed == strings.TrimSpace(notes)
+	}
+	return existing[:start] + block + existing[start+end+len(generatedEnd):], true
+}
+
+// generatedNotes returns the generated notes in the body of a Github release,
+// or the whole body if it has no generated block
+func generatedNotes(body string) string {
+	body = strings.Replace(body, "\r\n", "\n", -1)
+	start := strings.Index(body, generatedStart)
+	if start < 0 {
+		return strings.TrimSpace(body)
+	}
+	notes := body[start+len(generatedStart):]
+	if end := strings.Index(notes, generatedEnd); end >= 0 {
+		notes = notes[:end]
+	}
+	return strings.TrimSpace(notes)
+}
@@ -0,0 +1,153 @@
+package release
+
+import (
+	"html/template"
+	"io"
+	"strings"
+
+	bf "gopkg.in/russross/blackfriday.v2"
+)
+
+const pageStyle = `
+body { margin: 0; font: 16px/1.5 -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif; color: #24292e; background: #f6f8fa; }
+header, main { max-width: 52em; margin: 0 auto; padding: 1em 2em; }
+header { padding-top: 2em; }
+h1 { margin: 0.2em 0; }
+a { color: #0366d6; text-decoration: none; }
+a:hover { text-decoration: underline; }
+a.anchor { color: inherit; }
+nav, .meta { color: #586069; font-size: 0.9em; }
+.meta > * { margin-right: 1em; }
+section { margin: 1.5em 0; padding: 0.5em 1.5em; background: #fff; border-left: 4px solid #d1d5da; border-radius: 4px; }
+article { margin: 1em 0; }
+h4 { margin-bottom: 0.3em; }
+pre, code { font-family: SFMono-Regular, Consolas, Menlo, monospace; font-size: 0.9em; background: #f6f8fa; border-radius: 3px; }
+pre { padding: 1em; overflow: auto; }
+code { padding: 0.1em 0.3em; }
+pre code { padding: 0; }
+blockquote { margin: 0; padding-left: 1em; border-left: 3px solid #dfe2e5; color: #6a737d; }
+img { max-width: 100%; }
+table { border-collapse: collapse; }
+td, th { border: 1px solid #dfe2e5; padding: 0.3em 0.8em; }
+ul.releases { list-style: none; padding: 0; }
+ul.releases li { margin: 0.5em 0; padding: 0.8em 1.2em; background: #fff; border-radius: 4px; }
+`
+
+const pageTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Repository.Name}} {{.Title}}</title>
+<style>{{style}}</style>
+</head>
+<body>
+<header>
+{{- if .Index}}
+<nav><a href="{{.Index}}">All releases</a></nav>
+{{- end}}
+<h1><a href="{{.GithubURL}}">{{.Repository.Name}} {{.Title}}</a></h1>
+<div class="meta">
+{{- with .Date}}<time datetime="{{date "2006-01-02T15:04:05Z07:00" .}}">{{date "January 2, 2006" .}}</time>{{end}}
+{{- with .CompareURL}}<a href="{{.}}">Compare changes</a>{{end}}
+</div>
+</header>
+<main>
+{{- range .Sections}}
+<section id="{{anchor .Title}}" style="border-left-color: {{color .Color}}">
+<h2><a class="anchor" href="#{{anchor .Title}}">{{.Title}}</a></h2>
+{{- range .Scopes}}
+{{- if .Name}}
+<h3>{{.Name}}</h3>
+{{- end}}
+{{- range .PullRequests}}
+<article id="pr-{{.GetNumber}}">
+<h4><a href="{{.GetHTMLURL}}">#{{.GetNumber}}</a>: {{title .}}</h4>
+{{markdown (body .)}}
+</article>
+{{- end}}
+{{- end}}
+</section>
+{{- end}}
+</main>
+</body>
+</html>
+`
+
+var htmlTemplate = template.Must(template.New("page").Funcs(htmlFuncs(nil)).Parse(pageTemplate))
+
+// htmlPage is the data of release pages. Pages that are part of a site link to its index
+type htmlPage struct {
+	TemplateData
+	Index string
+}
+
+func htmlFuncs(r *Release) template.FuncMap {
+	return template.FuncMap{
+		"title":    r.DisplayTitle,
+		"body":     r.DisplayBody,
+		"date":     formatDate,
+		"anchor":   anchor,
+		"markdown": markdownToHTML,
+		"style":    func() template.CSS { return template.CSS(pageStyle) },
+		"color":    color,
+	}
+}
+
+// GenerateHTMLPage writes a self-contained HTML page with the release notes to
+// the provided writer. Sections have anchors, and pull request bodies are
+// converted from markdown
+func (r *Release) GenerateHTMLPage(w io.Writer) error {
+	return r.generateHTMLPage(w, "")
+}
+
+func (r *Release) generateHTMLPage(w io.Writer, index string) error {
+	t, err := htmlTemplate.Clone()
+	if err != nil {
+		return err
+	}
+	return t.Funcs(htmlFuncs(r)).Execute(w, htmlPage{
+		TemplateData: TemplateData{Release: r, Sections: r.GetPullRequestSections()},
+		Index:        index,
+	})
+}
+
+// markdownToHTML converts markdown to HTML. Raw HTML is left out and only safe
+// links are kept, since the markdown comes from pull request bodies
+func markdownToHTML(markdown string) template.HTML {
+	renderer := bf.NewHTMLRenderer(bf.HTMLRendererParameters{
+		Flags: bf.CommonHTMLFlags | bf.SkipHTML | bf.Safelink,
+	})
+	return template.HTML(bf.Run([]byte(markdown), bf.WithRenderer(renderer)))
+}
+
+// anchor returns the id of a heading, with lowercase letters, digits and dashes
+func anchor(heading string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(heading) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			if dash && b.Len() > 0 {
+				b.WriteRune('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	return b.String()
+}
+
+// color returns a hex section color as CSS, or a neutral color for anything else
+func color(hex string) template.CSS {
+	if len(hex) != 4 && len(hex) != 7 || hex[0] != '#' {
+		return "#d1d5da"
+	}
+	for _, r := range strings.ToLower(hex[1:]) {
+		if !(r >= '0' && r <= '9' || r >= 'a' && r <= 'f') {
+			return "#d1d5da"
+		}
+	}
+	return template.CSS(hex)
+}
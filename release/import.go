@@ -0,0 +1,62 @@
+package release
+
+import (
+	"fmt"
+
+	"github.com/franzwilhelm/gitflow-release-notes/changelog"
+	"github.com/franzwilhelm/gitflow-release-notes/githubutil"
+	"github.com/sirupsen/logrus"
+)
+
+// ImportResult reports how the version sections of a changelog matched the tags
+type ImportResult struct {
+	Matched             []string // The tags with a section, pushed unless their release exists
+	Failed              []string // The tags with a section that could not be pushed
+	TagsWithoutSection  []string
+	SectionsWithoutTags []string
+}
+
+// ImportChangelog creates Github releases from the version sections of a Keep
+// a Changelog style file. Sections are matched with tags by version, so
+// '1.2.3' matches the tag 'v1.2.3'. Existing releases are pushed with the
+// options, just like generated releases
+func ImportChangelog(content string, opts PushOptions) (ImportResult, error) {
+	var result ImportResult
+	tags, err := githubutil.GetAllTags()
+	if err != nil {
+		return result, fmt.Errorf("could not fetch tags: %v", err)
+	}
+	c := changelog.Parse(content)
+
+	matched := make(map[string]bool)
+	for _, section := range c.Sections {
+		v := section.Version()
+		if v == nil {
+			continue
+		}
+		var tag *githubutil.Tag
+		for i := range tags {
+			if tags[i].Version.Equal(v) {
+				tag = &tags[i]
+				break
+			}
+		}
+		if tag == nil {
+			result.SectionsWithoutTags = append(result.SectionsWithoutTags, section.Name)
+			continue
+		}
+		matched[tag.Data.Name] = true
+		if _, err := pushRelease(tag.Data.Name, section.Body, opts); err != nil {
+			logrus.WithError(err).WithField("tag", tag.Data.Name).Error("Could not push release to Github")
+			result.Failed = append(result.Failed, tag.Data.Name)
+		} else {
+			result.Matched = append(result.Matched, tag.Data.Name)
+		}
+	}
+	for _, tag := range tags {
+		if !matched[tag.Data.Name] {
+			result.TagsWithoutSection = append(result.TagsWithoutSection, tag.Data.Name)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,81 @@
+package release
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/franzwilhelm/gitflow-release-notes/ledger"
+	"github.com/sirupsen/logrus"
+)
+
+// Ledger records the releases published to Github and Slack. Releases are not
+// published to a destination again while their content is unchanged, unless
+// Force is used. Nothing is recorded if it's nil
+var Ledger *ledger.Ledger
+
+// Force publishes releases again even if the ledger shows they are unchanged
+var Force bool
+
+// ledgerTag returns the name of the release in the ledger
+func (r *Release) ledgerTag() string {
+	if r.TagName() != "" {
+		return r.TagName()
+	}
+	return r.Slug()
+}
+
+// publishedBefore checks if the content with the hash was the last content
+// published for the release to the destination
+func (r *Release) publishedBefore(destination, hash string) bool {
+	if Ledger == nil || Force || !Ledger.Published(r.ledgerTag(), destination, hash) {
+		return false
+	}
+	logrus.Infof("Skipping %s of %s, which is unchanged since it was last published. Use --force to ignore", destination, r.Title())
+	return true
+}
+
+// recordPublished records the content with the hash as published for the
+// release to the destination. Dry runs publish nothing, so they aren't recorded
+func (r *Release) recordPublished(destination, hash string) {
+	if Ledger == nil || DryRun {
+		return
+	}
+	if err := Ledger.Record(r.ledgerTag(), destination, hash, time.Now()); err != nil {
+		logrus.WithError(err).Errorf("Could not record %s of %s in the ledger", destination, r.Title())
+	}
+}
+
+// githubHash returns the hash of a release pushed to Github with the notes, and
+// the formats and files attached as assets
+func githubHash(notes string, formats, filenames []string) (string, error) {
+	parts := [][]byte{[]byte(notes)}
+	for _, format := range formats {
+		parts = append(parts, []byte(format))
+	}
+	for _, filename := range filenames {
+		hash, err := fileHash(filename)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, []byte(filepath.Base(filename)), []byte(hash))
+	}
+	return ledger.Hash(parts...), nil
+}
+
+// fileHash returns the hex encoded SHA-256 hash of the content of a file
+func fileHash(filename string) (string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -3,31 +3,60 @@ package release
 import (
 	"bytes"
 	"fmt"
-	"io"
+	"net/url"
+	"sort"
 	"strings"
 
-	"github.com/franzwilhelm/gitflow-release-notes/gitflow"
+	"github.com/franzwilhelm/gitflow-release-notes/diff"
 	"github.com/franzwilhelm/gitflow-release-notes/githubutil"
+	"github.com/franzwilhelm/gitflow-release-notes/ledger"
 	"github.com/franzwilhelm/gitflow-release-notes/slack"
 	"github.com/google/go-github/github"
 	version "github.com/hashicorp/go-version"
 	"github.com/sirupsen/logrus"
 )
 
+// Unreleased is the title of releases whose head is not a tag
+const Unreleased = "Unreleased"
+
+var filenameReplacer = strings.NewReplacer(".", "_", "/", "_")
+
 // Release is a wrapper of github data containing merged prs and commits between
-// two tags. The tag of the release is the one to create release notes for
+// two tags. The tag of the release is the one to create release notes for.
+// Releases with a branch, SHA or HEAD as head have no tag, and are unreleased
 type Release struct {
 	Tag          githubutil.Tag
+	Base         string      // The ref the release is compared against
+	Head         string      // The head ref of unreleased releases
+	Window       *TimeWindow // The merge time range of releases made from a time window
 	Repository   githubutil.Repository
 	Commits      []github.RepositoryCommit
 	PullRequests []github.PullRequest
+
+	document *Document // The document the release was created from, if any
+}
+
+// IsUnreleased checks if the release is made from a ref that is not a tag
+func (r *Release) IsUnreleased() bool {
+	return r.Head != ""
 }
 
 // Filename returns an appropriate filename based on the git tag of the release
-// For instance tag 'v1.2.3' returns 'v1_2_3.[fileExt]'
+// For instance tag 'v1.2.3' returns 'v1_2_3.[fileExt]', and the unreleased
+// head 'release/2.3' returns 'unreleased_release_2_3.[fileExt]'
 func (r *Release) Filename(fileExt string) string {
-	dotsRemoved := strings.Replace(r.TagName(), ".", "_", -1)
-	return fmt.Sprintf("%s.%s", dotsRemoved, fileExt)
+	return fmt.Sprintf("%s.%s", r.Slug(), fileExt)
+}
+
+// Slug returns the name of the release used in filenames and permalinks, like 'v1_2_3'
+func (r *Release) Slug() string {
+	name := r.TagName()
+	if r.IsUnreleased() {
+		name = strings.ToLower(Unreleased) + "_" + r.Head
+	} else if r.Window != nil {
+		name = r.Window.filename()
+	}
+	return filenameReplacer.Replace(name)
 }
 
 // TagName returns the git tag for a release
@@ -35,137 +64,476 @@ func (r *Release) TagName() string {
 	return r.Tag.Data.Name
 }
 
-// GithubURL returns the Github URL for the release
-func (r *Release) GithubURL() string {
-	return fmt.Sprintf("https://www.github.com/%s/releases/tag/%s", r.Repository.Full(), r.TagName())
+// Title returns the tag name of the release, 'Unreleased' if it has no tag,
+// or the time range of releases made from a time window
+func (r *Release) Title() string {
+	if r.IsUnreleased() {
+		return Unreleased
+	} else if r.Window != nil {
+		return r.Window.String()
+	}
+	return r.TagName()
 }
 
-// GetPullRequestSections returns the different pull request groups of a release
-func (r *Release) GetPullRequestSections() (
-	feature []github.PullRequest,
-	bugfix []github.PullRequest,
-	hotfix []github.PullRequest,
-	other []github.PullRequest,
-) {
+// CompareURL returns the Github URL comparing the base of the release with its
+// tag or head. Releases made from a time window have no comparison
+func (r *Release) CompareURL() string {
+	if r.Window != nil || r.Base == "" {
+		return ""
+	}
+	head := r.Head
+	if head == "" {
+		head = r.TagName()
+	}
+	return fmt.Sprintf("https://www.github.com/%s/compare/%s...%s", r.Repository.Full(), r.Base, head)
+}
 
-	for _, pr := range r.PullRequests {
-		branchName := pr.Head.GetRef()
-		prefix := strings.Split(branchName, "/")[0]
-		switch prefix {
-		case gitflow.Feature:
-			feature = append(feature, pr)
-		case gitflow.Bugfix:
-			bugfix = append(bugfix, pr)
-		case gitflow.Hotfix:
-			hotfix = append(hotfix, pr)
-		case gitflow.Release:
-			// skip
-		default:
-			other = append(other, pr)
-		}
+// GithubURL returns the Github URL for the release. Unreleased releases
+// link to the comparison between the base and the head, and releases made
+// from a time window to a search for the pull requests merged in it
+func (r *Release) GithubURL() string {
+	if r.IsUnreleased() {
+		return r.CompareURL()
+	} else if r.Window != nil {
+		return fmt.Sprintf("https://www.github.com/%s/pulls?q=%s", r.Repository.Full(), url.QueryEscape(r.Window.searchQuery(r.Base)))
 	}
-	return
+	return fmt.Sprintf("https://www.github.com/%s/releases/tag/%s", r.Repository.Full(), r.TagName())
 }
 
-// GenerateMarkdownChangelog writes a markdown changelog file to the provided writer
-func (r *Release) GenerateMarkdownChangelog(w io.Writer) error {
-	feature, bugfix, hotfix, other := r.GetPullRequestSections()
+// PushOptions configures how releases are pushed to Github
+type PushOptions struct {
+	// Overwrite updates the name and body of existing releases in place
+	Overwrite bool
+	// Recreate deletes and recreates existing releases instead, which loses
+	// their assets, reactions and flags
+	Recreate bool
+	// Draft creates releases as drafts, and updates existing drafts without
+	// Overwrite. Published releases are never turned into drafts
+	Draft bool
+	// Formats are the formats of the release notes attached to the release
+	Formats []string
+	// Assets are glob patterns of files attached to the release
+	Assets []string
+}
 
-	if err := writeMarkdownSection(w, "Features", feature); err != nil {
-		return err
+// PushToGithub pushes a release to github. If the release already exists,
+// it's only updated if the overwrite or recreate options are used. The notes
+// and files of the options are attached to pushed releases as assets. Releases
+// that are unchanged since they were recorded in the ledger are skipped.
+// Returns whether the release was pushed, or would be in a dry run
+func (r *Release) PushToGithub(opts PushOptions) (bool, error) {
+	if r.TagName() == "" {
+		return false, fmt.Errorf("can't push %s to Github, since it has no tag", r.Title())
 	}
-	if err := writeMarkdownSection(w, "Bug fixes", bugfix); err != nil {
-		return err
+	filenames, err := assetFiles(opts)
+	if err != nil {
+		return false, err
 	}
-	if err := writeMarkdownSection(w, "Hotfixes", hotfix); err != nil {
-		return err
+	buf := new(bytes.Buffer)
+	if err := r.GenerateMarkdownChangelog(buf); err != nil {
+		return false, err
 	}
-	return writeMarkdownSection(w, "Other", other)
-}
-
-func writeMarkdownSection(w io.Writer, title string, prs []github.PullRequest) error {
-	if prs == nil {
-		return nil
+	destination := "github:" + r.Repository.Full()
+	hash, err := githubHash(buf.String(), opts.Formats, filenames)
+	if err != nil {
+		return false, err
+	} else if r.publishedBefore(destination, hash) {
+		return false, nil
 	}
-	if _, err := fmt.Fprintf(w, "## %s:\n", title); err != nil {
-		return err
+	release, err := pushRelease(r.TagName(), buf.String(), opts)
+	if err != nil || release == nil {
+		return false, err
 	}
-	for _, pr := range prs {
-		if _, err := fmt.Fprintf(w, "#### [#%v](%s): %s\n%s\n\n",
-			pr.GetNumber(),
-			pr.GetHTMLURL(),
-			gitflow.RemovePrefixes(pr.GetTitle()),
-			pr.GetBody()); err != nil {
-			return err
-		}
+	if err := r.uploadAssets(release.GetID(), opts.Formats, filenames); err != nil {
+		return true, err
 	}
-	return nil
+	r.recordPublished(destination, hash)
+	return true, nil
 }
 
-// PushToGithub pushes a release to github. If the release already exists,
-// it won't be pushed if the overwrite argument is not present
-func (r *Release) PushToGithub(overwrite bool) error {
+// DiffWithGithub returns the unified diff of the body of the release in Github
+// and the body it would get with the generated notes, or an empty string if
+// they are equal. The body of releases that don't exist in Github yet is empty
+func (r *Release) DiffWithGithub() (string, error) {
+	if r.TagName() == "" {
+		return "", fmt.Errorf("can't compare %s with Github, since it has no tag", r.Title())
+	}
 	buf := new(bytes.Buffer)
 	if err := r.GenerateMarkdownChangelog(buf); err != nil {
-		return err
+		return "", err
 	}
 	release, err := githubutil.GetRelease(r.TagName())
 	if err != nil {
-		logrus.Infof("Pusing release %s to Github", r.TagName())
-		return githubutil.CreateRelease(r.TagName(), buf.String())
-	} else if overwrite {
-		logrus.Warnf("Overwriting release %s in Github", r.TagName())
+		return "", fmt.Errorf("could not look up release: %v", err)
+	}
+	old := strings.Replace(release.GetBody(), "\r\n", "\n", -1)
+	body, _ := releaseBody(old, buf.String())
+	return diff.Unified(old, body, "github/"+r.TagName(), "generated/"+r.TagName()), nil
+}
+
+// pushRelease creates the release of a tag in Github. If the release already
+// exists, it's updated in place or recreated depending on the options. Only the
+// generated block of existing release bodies is replaced with the notes, and
+// bodies with hand-written text but no generated block are only replaced when
+// recreating or forced.
+// Returns the pushed release, or nil if the existing release was skipped. In dry
+// runs the release is printed, and releases that would be created have no ID
+func pushRelease(tagName, notes string, opts PushOptions) (*github.RepositoryRelease, error) {
+	release, err := githubutil.GetRelease(tagName)
+	if err != nil {
+		return nil, fmt.Errorf("could not look up release: %v", err)
+	}
+	old := strings.Replace(release.GetBody(), "\r\n", "\n", -1)
+	body, kept := releaseBody(old, notes)
+	if release == nil && DryRun {
+		draft := ""
+		if opts.Draft {
+			draft = "draft "
+		}
+		DryRunf("would create %srelease %s in Github:\n%s", draft, tagName, body)
+		return &github.RepositoryRelease{}, nil
+	} else if release == nil && opts.Draft {
+		logrus.Infof("Pushing draft release %s to Github", tagName)
+		return githubutil.CreateRelease(tagName, body, true)
+	} else if release == nil {
+		logrus.Infof("Pushing release %s to Github", tagName)
+		return githubutil.CreateRelease(tagName, body, false)
+	} else if opts.Recreate && DryRun {
+		DryRunf("would delete and recreate release %s in Github:\n%s", tagName, body)
+		return &github.RepositoryRelease{}, nil
+	} else if opts.Recreate {
+		logrus.Warnf("Recreating release %s in Github", tagName)
 		if err := githubutil.DeleteRelease(*release.ID); err != nil {
-			return err
+			return nil, err
 		}
-		return githubutil.CreateRelease(r.TagName(), buf.String())
-	} else {
-		logrus.Warnf("Skipping push of existing release %s. Use --overwrite to ignore", r.TagName())
+		return githubutil.CreateRelease(tagName, body, opts.Draft || release.GetDraft())
+	} else if (opts.Draft && release.GetDraft() || opts.Overwrite) && !kept && !Force {
+		logrus.Warnf("Skipping push of existing release %s, since its body has no %s and %s markers around the generated notes and would be replaced. Add the markers in Github, or use --recreate or --force to replace it", tagName, generatedStart, generatedEnd)
+		return nil, nil
+	} else if (opts.Draft && release.GetDraft() || opts.Overwrite) && DryRun {
+		if body == old {
+			logrus.Infof("Release %s in Github is up to date", tagName)
+		} else {
+			DryRunf("would update release %s in Github:\n%s", tagName, diff.Unified(old, body, "github/"+tagName, "generated/"+tagName))
+		}
+		return release, nil
+	} else if opts.Draft && release.GetDraft() {
+		logrus.Infof("Updating draft release %s in Github", tagName)
+		return release, githubutil.EditRelease(release.GetID(), tagName, body)
+	} else if opts.Overwrite {
+		logrus.Warnf("Overwriting release %s in Github", tagName)
+		return release, githubutil.EditRelease(release.GetID(), tagName, body)
 	}
-	return nil
+	logrus.Warnf("Skipping push of existing release %s. Use --overwrite to ignore", tagName)
+	return nil, nil
+}
+
+// PublishDraft publishes the draft release of a tag in Github. Returns the
+// generated notes of the release as they were reviewed in Github
+func PublishDraft(tagName string) (string, error) {
+	release, err := githubutil.GetRelease(tagName)
+	if err != nil {
+		return "", fmt.Errorf("could not look up release: %v", err)
+	} else if release == nil {
+		return "", fmt.Errorf("tag %s has no release in Github", tagName)
+	}
+	notes := generatedNotes(release.GetBody())
+	if !release.GetDraft() {
+		logrus.Warnf("Release %s is already published", tagName)
+		return notes, nil
+	}
+	if DryRun {
+		DryRunf("would publish draft release %s in Github", tagName)
+		return notes, nil
+	}
+	logrus.Infof("Publishing release %s in Github", tagName)
+	return notes, githubutil.PublishRelease(release.GetID())
 }
 
-// PushToSlack pushes release notes to the slack channel specified
+// PushToSlack pushes release notes to the slack channel specified, unless the
+// same message was recorded in the ledger. Dry runs print the webhook payload
+// instead
 func (r *Release) PushToSlack(channel, iconURL string) error {
-	feature, bugfix, hotfix, other := r.GetPullRequestSections()
+	return r.postSlackMessage(r.SlackMessage(channel, iconURL))
+}
 
+// PushNotesToSlack pushes release notes written in markdown, like the notes of
+// the release of a tag in Github, to the slack channel specified
+func PushNotesToSlack(tagName, notes, channel, iconURL string) error {
+	r := &Release{Repository: githubutil.Repo}
+	r.Tag.Data.Name = tagName
+	message := r.SlackMessage(channel, iconURL)
+	attachment := slack.Attachment{Color: otherSection.Color}
+	attachment.UseMarkdown(notes)
+	message.Attachments = []slack.Attachment{attachment}
+	return r.postSlackMessage(message)
+}
+
+// postSlackMessage posts a slack message with the notes of the release, unless
+// the same message was recorded in the ledger. Dry runs print the payload instead
+func (r *Release) postSlackMessage(message *slack.WebhookMessage) error {
+	channel := message.Channel
+	buf := new(bytes.Buffer)
+	if err := writeJSON(buf, message); err != nil {
+		return err
+	}
+	destination := "slack:" + channel
+	hash := ledger.Hash(buf.Bytes())
+	if r.publishedBefore(destination, hash) {
+		return nil
+	} else if DryRun {
+		DryRunf("would post to slack:\n%s", buf.String())
+		return nil
+	} else if err := slack.PostWebhook(message); err != nil {
+		return err
+	}
+	r.recordPublished(destination, hash)
+	return nil
+}
+
+// SlackMessage returns the slack message with the release notes. The message
+// is posted to the default channel of the webhook if channel is empty
+func (r *Release) SlackMessage(channel, iconURL string) *slack.WebhookMessage {
 	var attachments []slack.Attachment
-	addSlackAttachment(&attachments, "Features", "#315cfd", feature)
-	addSlackAttachment(&attachments, "Bug fixes", "#d80f5c", bugfix)
-	addSlackAttachment(&attachments, "Hotfixes", "#d80f5c", hotfix)
-	addSlackAttachment(&attachments, "", "#2a284f", other)
+	for _, section := range r.GetPullRequestSections() {
+		attachments = append(attachments, r.slackAttachment(section))
+	}
 
-	return slack.PostWebhook(&slack.WebhookMessage{
+	text := fmt.Sprintf("New release: <%s|%s@%s> :tada:", r.GithubURL(), r.Repository.Name, r.TagName())
+	if r.IsUnreleased() {
+		text = fmt.Sprintf("Unreleased changes: <%s|%s@%s>", r.GithubURL(), r.Repository.Name, r.Head)
+	} else if r.Window != nil {
+		text = fmt.Sprintf("Changes in %s: <%s|%s>", r.Repository.Name, r.GithubURL(), r.Window)
+	}
+	return &slack.WebhookMessage{
 		Channel:     channel,
 		IconURL:     iconURL,
 		Username:    "Release Notes",
-		Text:        fmt.Sprintf("New release: <%s|%s@%s> :tada:", r.GithubURL(), r.Repository.Name, r.TagName()),
+		Text:        text,
 		Attachments: attachments,
-	})
+	}
 }
 
-func addSlackAttachment(attachments *[]slack.Attachment, title, color string, prs []github.PullRequest) {
-	if prs != nil {
-		attachment := slack.Attachment{Title: title, Color: color}
-		attachment.UsePullRequests(prs)
-		*attachments = append(*attachments, attachment)
+func (r *Release) slackAttachment(section Section) slack.Attachment {
+	attachment := slack.Attachment{Title: section.Title, Color: section.Color}
+	for _, scope := range section.Scopes {
+		attachment.UsePullRequests(scope.Name, scope.PullRequests, r)
 	}
+	return attachment
 }
 
 // GenerateReleasesBetweenTags generates a release array containing all releases
 // between two tags. For instance sending in v1.10.0 and v1.10.4 will generate
 // a release array containing v1.10.0, v1.10.1, v1.10.2, v1.10.3 and v1.10.4
 func GenerateReleasesBetweenTags(baseVersion, headVersion *version.Version, tagPrefix string) ([]Release, error) {
-	// Fetch the latest 100 tags and pull requests
+	tags, prMap, err := fetchTagsAndPullRequests()
+	if err != nil {
+		return nil, err
+	}
+	return releasesBetweenTags(tags, prMap, baseVersion, headVersion, tagPrefix)
+}
+
+// GenerateReleasesBetweenRefs generates a release array containing all releases
+// between two refs, which can be tags, branches, SHAs or HEAD. A base tag is
+// included in the releases, just like in GenerateReleasesBetweenTags. If the
+// head is not a tag, the changes after the last tag are added as an unreleased
+// release. Passing the same ref as base and head generates the release for a tag,
+// or the unreleased changes since the newest tag reachable from other refs
+func GenerateReleasesBetweenRefs(base, head string) ([]Release, error) {
+	tags, prMap, err := fetchTagsAndPullRequests()
+	if err != nil {
+		return nil, err
+	}
+
+	baseTag, baseIsTag := findTag(tags, base)
+	headTag, headIsTag := findTag(tags, head)
+	if baseIsTag && headIsTag {
+		return releasesBetweenTags(tags, prMap, baseTag.Version, headTag.Version, baseTag.Prefix())
+	}
+
+	compareBase := base
+	if baseIsTag {
+		if compareBase, err = tagBefore(tags, baseTag); err != nil {
+			return nil, err
+		}
+	} else if base == head {
+		// Like GenerateUnreleased, tags on other lines, like hotfixes of older
+		// versions, are not the base of the ref
+		latest, err := latestTagReachableFrom(tags, head)
+		if err != nil {
+			return nil, err
+		}
+		compareBase = latest.Data.Name
+	}
+
+	commits, err := githubutil.CompareCommits(compareBase, head)
+	if err != nil {
+		return nil, fmt.Errorf("could not get commits between '%s' and '%s': %v", compareBase, head, err)
+	}
+
+	tagsBySha := make(map[string]githubutil.Tag)
+	for _, tag := range tags {
+		tagsBySha[tag.Data.Target.Sha] = tag
+	}
+
+	excluded := excludedCommits(commits, prMap)
+	var releases []Release
+	release := Release{Base: compareBase, Repository: githubutil.Repo}
+	for _, commit := range commits {
+		release.addCommit(commit, prMap, excluded)
+		if tag, ok := tagsBySha[commit.GetSHA()]; ok {
+			release.Tag = tag
+			releases = append(releases, release)
+			release = Release{Base: tag.Data.Name, Repository: githubutil.Repo}
+		}
+	}
+	if len(release.Commits) > 0 && !headIsTag {
+		release.Head = head
+		releases = append(releases, release)
+	}
+	return releases, nil
+}
+
+// LatestTag fetches the tag with the highest version that is reachable from the ref
+func LatestTag(ref string) (*githubutil.Tag, error) {
 	tags, err := githubutil.GetTags()
 	if err != nil {
 		return nil, fmt.Errorf("could not fetch tags: %v", err)
 	}
+	return latestTagReachableFrom(tags, ref)
+}
+
+// GenerateUnreleased generates an unreleased release containing the changes on
+// the develop branch since the newest tag reachable from the stable branch
+func GenerateUnreleased(stable, develop string) (*Release, error) {
+	tags, prMap, err := fetchTagsAndPullRequests()
+	if err != nil {
+		return nil, err
+	}
+	base, err := latestTagReachableFrom(tags, stable)
+	if err != nil {
+		return nil, err
+	}
+
+	commits, err := githubutil.CompareCommits(base.Data.Name, develop)
+	if err != nil {
+		return nil, fmt.Errorf("could not get commits between '%s' and '%s': %v", base.Data.Name, develop, err)
+	}
+	excluded := excludedCommits(commits, prMap)
+	release := &Release{Base: base.Data.Name, Head: develop, Repository: githubutil.Repo}
+	for _, commit := range commits {
+		release.addCommit(commit, prMap, excluded)
+	}
+	return release, nil
+}
+
+// addCommit adds a commit to the release, along with its pull request if it's
+// a merge commit. Commits of excluded pull requests are left out
+func (r *Release) addCommit(commit github.RepositoryCommit, prMap map[string]*github.PullRequest, excluded map[string]bool) {
+	if excluded[commit.GetSHA()] {
+		return
+	}
+	r.Commits = append(r.Commits, commit)
+	if pr, ok := prMap[commit.GetSHA()]; ok {
+		r.PullRequests = append(r.PullRequests, *pr)
+	}
+}
+
+// excludedCommits returns the SHAs of the commits of excluded pull requests.
+// These are their merge commits, and the commits of their branches that were
+// merged with them, which are reachable from the merge commit but not from its
+// first parent. Only the compared commits are looked at
+func excludedCommits(commits []github.RepositoryCommit, prMap map[string]*github.PullRequest) map[string]bool {
+	parents := make(map[string][]string)
+	for _, commit := range commits {
+		parents[commit.GetSHA()] = []string{}
+		for _, parent := range commit.Parents {
+			parents[commit.GetSHA()] = append(parents[commit.GetSHA()], parent.GetSHA())
+		}
+	}
+	ancestors := func(shas []string) map[string]bool {
+		seen := make(map[string]bool)
+		for len(shas) > 0 {
+			sha := shas[len(shas)-1]
+			shas = shas[:len(shas)-1]
+			if _, compared := parents[sha]; compared && !seen[sha] {
+				seen[sha] = true
+				shas = append(shas, parents[sha]...)
+			}
+		}
+		return seen
+	}
+	excluded := make(map[string]bool)
+	for _, commit := range commits {
+		sha := commit.GetSHA()
+		if pr, ok := prMap[sha]; !ok || !IsExcluded(*pr) {
+			continue
+		}
+		excluded[sha] = true
+		if len(parents[sha]) < 2 {
+			continue
+		}
+		mainline := ancestors(parents[sha][:1])
+		for branchSha := range ancestors(parents[sha][1:]) {
+			if !mainline[branchSha] {
+				excluded[branchSha] = true
+			}
+		}
+	}
+	return excluded
+}
+
+// fetchTagsAndPullRequests fetches the latest 100 tags and pull requests
+func fetchTagsAndPullRequests() ([]githubutil.Tag, map[string]*github.PullRequest, error) {
+	tags, err := githubutil.GetTags()
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not fetch tags: %v", err)
+	}
 	prMap, err := githubutil.GetPullRequests()
 	if err != nil {
-		return nil, fmt.Errorf("could not fetch pull requests: %v", err)
+		return nil, nil, fmt.Errorf("could not fetch pull requests: %v", err)
 	}
+	return tags, prMap, nil
+}
 
+func findTag(tags []githubutil.Tag, name string) (githubutil.Tag, bool) {
+	for _, tag := range tags {
+		if tag.Data.Name == name {
+			return tag, true
+		}
+	}
+	return githubutil.Tag{}, false
+}
+
+func tagBefore(tags []githubutil.Tag, tag githubutil.Tag) (string, error) {
+	for i := range tags {
+		if tags[i].Version.Equal(tag.Version) && i+1 < len(tags) {
+			return tags[i+1].Data.Name, nil
+		}
+	}
+	return "", fmt.Errorf("could not find the tag before %s", tag.Data.Name)
+}
+
+// latestTagReachableFrom finds the tag with the highest version that is reachable from the ref
+func latestTagReachableFrom(tags []githubutil.Tag, ref string) (*githubutil.Tag, error) {
+	sorted := make([]githubutil.Tag, len(tags))
+	copy(sorted, tags)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Version.GreaterThan(sorted[j].Version)
+	})
+	for i := range sorted {
+		reachable, err := githubutil.IsAncestor(sorted[i].Data.Name, ref)
+		if err != nil {
+			return nil, fmt.Errorf("could not compare tag %s with %s: %v", sorted[i].Data.Name, ref, err)
+		}
+		if reachable {
+			return &sorted[i], nil
+		}
+	}
+	return nil, fmt.Errorf("could not find a tag reachable from %s", ref)
+}
+
+func releasesBetweenTags(tags []githubutil.Tag, prMap map[string]*github.PullRequest, baseVersion, headVersion *version.Version, tagPrefix string) ([]Release, error) {
 	// Find the tag before the base version and use it as the new base
 	for i, tag := range tags {
 		if tag.Version.Equal(baseVersion) {
@@ -182,11 +550,14 @@ func GenerateReleasesBetweenTags(baseVersion, headVersion *version.Version, tagP
 		return nil, fmt.Errorf("could not get commits between tag '%s' and '%s': %v", baseTag, headTag, err)
 	}
 
+	excluded := excludedCommits(commits, prMap)
 	var releases []Release
 	j := 0
+	previousTag := baseTag
 	for i := len(tags) - 1; i >= 0; i-- {
 		release := Release{
 			Tag:        tags[i],
+			Base:       previousTag,
 			Repository: githubutil.Repo,
 		}
 		if !tags[i].IsBetween(baseVersion, headVersion) {
@@ -195,10 +566,7 @@ func GenerateReleasesBetweenTags(baseVersion, headVersion *version.Version, tagP
 		found := false
 		for ; j < len(commits); j++ {
 			commit := commits[j]
-			release.Commits = append(release.Commits, commit)
-			if pr, ok := prMap[commit.GetSHA()]; ok {
-				release.PullRequests = append(release.PullRequests, *pr)
-			}
+			release.addCommit(commit, prMap, excluded)
 			if commit.GetSHA() == tags[i].Data.Target.Sha {
 				found = true
 				j++
@@ -209,6 +577,7 @@ func GenerateReleasesBetweenTags(baseVersion, headVersion *version.Version, tagP
 			return nil, fmt.Errorf("Could not find the commit for tag %v. Is the original tag commit deleted?", tags[i])
 		}
 		releases = append(releases, release)
+		previousTag = tags[i].Data.Name
 	}
 	return releases, nil
 }
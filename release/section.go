@@ -0,0 +1,246 @@
+package release
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/franzwilhelm/gitflow-release-notes/gitflow"
+	"github.com/google/go-github/github"
+)
+
+// SectionRule decides which pull requests are grouped in a section of the
+// release notes. A pull request matches the rule if it matches any of the
+// branch prefixes, labels, title regular expressions, authors or Conventional
+// Commits types. A rule without any of these is a catch-all
+type SectionRule struct {
+	Title    string   `mapstructure:"title"`
+	Order    int      `mapstructure:"order"`
+	Color    string   `mapstructure:"color"`
+	Bump     Bump     `mapstructure:"bump"`
+	Skip     bool     `mapstructure:"skip"`
+	Branches []string `mapstructure:"branches"`
+	Labels   []string `mapstructure:"labels"`
+	Titles   []string `mapstructure:"titles"`
+	Authors  []string `mapstructure:"authors"`
+	Types    []string `mapstructure:"types"`
+
+	titleRegexps []*regexp.Regexp
+}
+
+// Section is a titled group of pull requests in the release notes
+type Section struct {
+	Title        string
+	Color        string
+	Bump         Bump
+	PullRequests []github.PullRequest
+	Scopes       []Scope
+}
+
+// Scope is a group of pull requests within a section sharing the same
+// Conventional Commits scope. Pull requests without a scope have an empty name
+type Scope struct {
+	Name         string
+	PullRequests []github.PullRequest
+}
+
+func (s *Section) add(pr github.PullRequest, scope string) {
+	s.PullRequests = append(s.PullRequests, pr)
+	for i := range s.Scopes {
+		if s.Scopes[i].Name == scope {
+			s.Scopes[i].PullRequests = append(s.Scopes[i].PullRequests, pr)
+			return
+		}
+	}
+	// Pull requests without a scope are listed first, so they don't end up below a scope heading
+	newScope := Scope{Name: scope, PullRequests: []github.PullRequest{pr}}
+	if scope == "" {
+		s.Scopes = append([]Scope{newScope}, s.Scopes...)
+	} else {
+		s.Scopes = append(s.Scopes, newScope)
+	}
+}
+
+// DefaultSectionRules groups pull requests by their GitFlow branch prefix or
+// Conventional Commits type, and skips release branches
+var DefaultSectionRules = []SectionRule{
+	{Title: "Features", Color: "#315cfd", Bump: Minor, Branches: []string{gitflow.Feature}, Types: []string{"feat"}},
+	{Title: "Bug fixes", Color: "#d80f5c", Bump: Patch, Branches: []string{gitflow.Bugfix}, Types: []string{"fix"}},
+	{Title: "Hotfixes", Color: "#d80f5c", Bump: Patch, Branches: []string{gitflow.Hotfix}},
+	{Skip: true, Branches: []string{gitflow.Release}},
+	otherSection,
+}
+
+var sectionRules = DefaultSectionRules
+
+// LabelPrecedence makes labels take precedence over the other matchers of the
+// section rules. A pull request is then put in the first section matching one
+// of its labels, before falling back to the first section matching it otherwise
+var LabelPrecedence bool
+
+// ExcludeLabels are the labels that leave pull requests and their commits out
+// of all releases
+var ExcludeLabels = []string{"skip-changelog"}
+
+// IsExcluded checks if a pull request has one of the exclude labels
+func IsExcluded(pr github.PullRequest) bool {
+	return hasLabel(pr, ExcludeLabels)
+}
+
+func hasLabel(pr github.PullRequest, names []string) bool {
+	for _, label := range pr.Labels {
+		for _, name := range names {
+			if strings.EqualFold(label.GetName(), name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// otherSection is the catch-all section added to section rules without one
+var otherSection = SectionRule{Title: "Other", Color: "#2a284f", Bump: None}
+
+// UseSectionRules validates the section rules and uses them to group the pull
+// requests of all releases. Rules are matched in order, so the first match wins.
+// If no rule is a catch-all, an Other section is added last for the pull
+// requests not matching any rule. Use a catch-all with skip to leave them out
+func UseSectionRules(rules []SectionRule) error {
+	rules = append([]SectionRule(nil), rules...)
+	catchAll := false
+	order := 0
+	for i := range rules {
+		catchAll = catchAll || rules[i].isCatchAll()
+		if rules[i].Order > order {
+			order = rules[i].Order
+		}
+	}
+	if !catchAll {
+		other := otherSection
+		other.Order = order
+		rules = append(rules, other)
+	}
+	for i := range rules {
+		rule := &rules[i]
+		if rule.Title == "" && !rule.Skip {
+			return fmt.Errorf("section rule %v needs a title", i+1)
+		}
+		switch rule.Bump {
+		case "", Major, Minor, Patch, None:
+		default:
+			return fmt.Errorf("section %s has an invalid bump %s", rule.Title, rule.Bump)
+		}
+		rule.titleRegexps = nil
+		for _, title := range rule.Titles {
+			re, err := regexp.Compile(title)
+			if err != nil {
+				return fmt.Errorf("section %s has an invalid title regexp: %v", rule.Title, err)
+			}
+			rule.titleRegexps = append(rule.titleRegexps, re)
+		}
+	}
+	sectionRules = rules
+	return nil
+}
+
+func (s *SectionRule) isCatchAll() bool {
+	return len(s.Branches) == 0 && len(s.Labels) == 0 && len(s.Titles) == 0 && len(s.Authors) == 0 && len(s.Types) == 0
+}
+
+// Matches checks if a pull request belongs to the section. The commit type is
+// the Conventional Commits type of the pull request, if any
+func (s *SectionRule) Matches(pr github.PullRequest, commitType string) bool {
+	if s.isCatchAll() {
+		return true
+	}
+	prefix := strings.Split(pr.Head.GetRef(), "/")[0]
+	for _, branch := range s.Branches {
+		if prefix == branch {
+			return true
+		}
+	}
+	if hasLabel(pr, s.Labels) {
+		return true
+	}
+	for _, re := range s.titleRegexps {
+		if re.MatchString(pr.GetTitle()) {
+			return true
+		}
+	}
+	for _, author := range s.Authors {
+		if strings.EqualFold(pr.User.GetLogin(), author) {
+			return true
+		}
+	}
+	for _, t := range s.Types {
+		if commitType != "" && strings.EqualFold(commitType, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// sectionIndex returns the index of the section rule a pull request belongs
+// to, or -1 if it doesn't match any rule
+func sectionIndex(pr github.PullRequest, commitType string) int {
+	if LabelPrecedence {
+		for i, rule := range sectionRules {
+			if hasLabel(pr, rule.Labels) {
+				return i
+			}
+		}
+	}
+	for i, rule := range sectionRules {
+		if rule.Matches(pr, commitType) {
+			return i
+		}
+	}
+	return -1
+}
+
+// GetPullRequestSections groups the pull requests of a release by the section
+// rules. Only sections containing pull requests are returned, sorted by order.
+// Breaking changes are listed in their own section before all the others.
+// With Conventional Commits enabled, the pull requests of each section are
+// grouped by scope as well. Releases created from a document keep its sections
+func (r *Release) GetPullRequestSections() []Section {
+	if r.document != nil {
+		return r.documentSections()
+	}
+	sections := make([]Section, len(sectionRules))
+	for i, rule := range sectionRules {
+		sections[i] = Section{Title: rule.Title, Color: rule.Color, Bump: rule.Bump}
+	}
+	breaking := Section{Title: BreakingChanges, Color: breakingChangesColor, Bump: Major}
+	for _, pr := range r.PullRequests {
+		message, _ := r.ConventionalMessage(pr)
+		i := sectionIndex(pr, message.Type)
+		if i >= 0 && sectionRules[i].Skip {
+			continue
+		}
+		if message.Breaking || IsBreaking(pr) {
+			breaking.add(pr, message.Scope)
+		} else if i >= 0 {
+			sections[i].add(pr, message.Scope)
+		}
+	}
+
+	var indices []int
+	for i, section := range sections {
+		if section.PullRequests != nil {
+			indices = append(indices, i)
+		}
+	}
+	sort.SliceStable(indices, func(i, j int) bool {
+		return sectionRules[indices[i]].Order < sectionRules[indices[j]].Order
+	})
+	var result []Section
+	if breaking.PullRequests != nil {
+		result = append(result, breaking)
+	}
+	for _, index := range indices {
+		result = append(result, sections[index])
+	}
+	return result
+}
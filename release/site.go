@@ -0,0 +1,105 @@
+package release
+
+import (
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+
+	"github.com/franzwilhelm/gitflow-release-notes/githubutil"
+	"github.com/sirupsen/logrus"
+)
+
+const indexTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Repository.Name}} releases</title>
+<style>{{style}}</style>
+</head>
+<body>
+<header>
+<h1><a href="https://www.github.com/{{.Repository.Full}}">{{.Repository.Name}}</a> releases</h1>
+<div class="meta"><a href="releases.json">releases.json</a></div>
+</header>
+<main>
+<ul class="releases">
+{{- range .Entries}}
+<li id="{{.Slug}}">
+<a href="{{.Permalink}}"><strong>{{.Title}}</strong></a>
+<span class="meta">
+{{- with .Date}} <time datetime="{{date "2006-01-02T15:04:05Z07:00" .}}">{{date "January 2, 2006" .}}</time>{{end}}
+{{- range $i, $section := .Sections}}{{if $i}},{{end}} {{len .PullRequests}} {{.Title}}{{end}}
+</span>
+</li>
+{{- end}}
+</ul>
+</main>
+</body>
+</html>
+`
+
+var siteIndexTemplate = template.Must(template.New("index").Funcs(htmlFuncs(nil)).Parse(indexTemplate))
+
+// SiteEntry is a release in the releases.json of a site, with the permalink of its page
+type SiteEntry struct {
+	Slug      string `json:"slug"`
+	Permalink string `json:"permalink"`
+	Document
+}
+
+// GenerateSite writes a static site with the releases to a directory. Each
+// release gets a page with the permalink <slug>/, next to a release.json with
+// its document. The index lists the releases newest first, and releases.json
+// contains all of them, for search
+func GenerateSite(dir string, repository githubutil.Repository, releases []Release) error {
+	var entries []SiteEntry
+	for i := len(releases) - 1; i >= 0; i-- {
+		r := &releases[i]
+		entry := SiteEntry{Slug: r.Slug(), Permalink: r.Slug() + "/", Document: r.Document()}
+		releaseDir := filepath.Join(dir, entry.Slug)
+		if err := os.MkdirAll(releaseDir, 0755); err != nil {
+			return err
+		}
+		if err := writeFile(filepath.Join(releaseDir, "index.html"), func(f *os.File) error {
+			return r.generateHTMLPage(f, "../")
+		}); err != nil {
+			return err
+		}
+		if err := writeFile(filepath.Join(releaseDir, "release.json"), func(f *os.File) error {
+			return writeJSON(f, entry.Document)
+		}); err != nil {
+			return err
+		}
+		logrus.WithField("release", r.Title()).Infof("Wrote page to %s", releaseDir)
+		entries = append(entries, entry)
+	}
+
+	if entries == nil {
+		entries = []SiteEntry{}
+	}
+	if err := writeFile(filepath.Join(dir, "releases.json"), func(f *os.File) error {
+		return writeJSON(f, entries)
+	}); err != nil {
+		return err
+	}
+	return writeFile(filepath.Join(dir, "index.html"), func(f *os.File) error {
+		return siteIndexTemplate.Execute(f, &struct {
+			Repository githubutil.Repository
+			Entries    []SiteEntry
+		}{repository, entries})
+	})
+}
+
+func writeFile(filename string, write func(f *os.File) error) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return fmt.Errorf("could not write %s: %v", filename, err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,109 @@
+package release
+
+import (
+	"fmt"
+	"io"
+	"text/template"
+	"time"
+
+	"github.com/franzwilhelm/gitflow-release-notes/gitflow"
+	"github.com/google/go-github/github"
+)
+
+// DefaultTemplate renders a heading for each section and Conventional Commits
+// scope, followed by the title and body of each pull request
+const DefaultTemplate = `{{range .Sections}}## {{.Title}}:
+{{range .Scopes}}{{if .Name}}### {{.Name}}
+{{end}}{{range .PullRequests}}#### [#{{.GetNumber}}]({{.GetHTMLURL}}): {{title .}}
+{{body .}}
+
+{{end}}{{end}}{{end}}`
+
+var changelogTemplate = template.Must(parseTemplate(DefaultTemplate))
+
+// TemplateData is the data markdown templates are executed with. Besides the
+// sections, templates have access to the fields and methods of the release,
+// like .Title, .TagName, .PullRequests, .Commits and .CompareURL
+type TemplateData struct {
+	*Release
+	Sections []Section
+}
+
+// UseTemplate parses a text/template and uses it to render the markdown of all releases.
+// Along with the builtin functions, templates can use title and body to get the
+// text shown for a pull request, removePrefixes, truncate, shortSHA, date and now
+func UseTemplate(text string) error {
+	t, err := parseTemplate(text)
+	if err != nil {
+		return fmt.Errorf("could not parse template: %v", err)
+	}
+	changelogTemplate = t
+	return nil
+}
+
+func parseTemplate(text string) (*template.Template, error) {
+	return template.New("changelog").Funcs(templateFuncs(nil)).Parse(text)
+}
+
+// templateFuncs returns the template functions, with title and body bound to the release
+func templateFuncs(r *Release) template.FuncMap {
+	return template.FuncMap{
+		"title":          r.DisplayTitle,
+		"body":           r.DisplayBody,
+		"removePrefixes": gitflow.RemovePrefixes,
+		"truncate":       truncate,
+		"shortSHA":       shortSHA,
+		"date":           formatDate,
+		"now":            time.Now,
+	}
+}
+
+// GenerateMarkdownChangelog writes a markdown changelog file to the provided writer
+func (r *Release) GenerateMarkdownChangelog(w io.Writer) error {
+	t, err := changelogTemplate.Clone()
+	if err != nil {
+		return err
+	}
+	return t.Funcs(templateFuncs(r)).Execute(w, TemplateData{Release: r, Sections: r.GetPullRequestSections()})
+}
+
+// truncate shortens a string to at most length characters, ending it with an
+// ellipsis if anything was cut. The string is last, so it can be piped
+func truncate(length int, s string) string {
+	runes := []rune(s)
+	if length < 1 || len(runes) <= length {
+		return s
+	}
+	return string(runes[:length-1]) + "…"
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
+
+// formatDate formats a time.Time or github.Timestamp, or pointers to them, with
+// a Go time layout. Nil times are formatted as an empty string
+func formatDate(layout string, value interface{}) (string, error) {
+	switch t := value.(type) {
+	case time.Time:
+		return t.Format(layout), nil
+	case *time.Time:
+		if t == nil {
+			return "", nil
+		}
+		return t.Format(layout), nil
+	case github.Timestamp:
+		return t.Format(layout), nil
+	case *github.Timestamp:
+		if t == nil {
+			return "", nil
+		}
+		return t.Format(layout), nil
+	case nil:
+		return "", nil
+	}
+	return "", fmt.Errorf("can't format %T as a date", value)
+}
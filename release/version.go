@@ -0,0 +1,100 @@
+package release
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	version "github.com/hashicorp/go-version"
+)
+
+// Bump is the part of a semantic version to increment for a release
+type Bump string
+
+const (
+	// Major is the bump for releases containing breaking changes
+	Major Bump = "major"
+	// Minor is the bump for releases containing features
+	Minor Bump = "minor"
+	// Patch is the bump for releases only containing fixes
+	Patch Bump = "patch"
+	// None is the bump of sections that don't call for a release on their own,
+	// like Other
+	None Bump = "none"
+)
+
+// ErrNothingToRelease is returned when suggesting the version of a release
+// without pull requests calling for a bump
+var ErrNothingToRelease = errors.New("nothing to release")
+
+// VersionSuggestion holds the suggested next version of an unreleased release
+type VersionSuggestion struct {
+	Current string `json:"current"`
+	Next    string `json:"next"`
+	Bump    Bump   `json:"bump"`
+}
+
+// Bump returns the semantic version bump the pull requests of the release calls for.
+// Breaking changes bumps the major version, and otherwise the highest bump of
+// the sections is used. By default features bumps the minor version, bug fixes
+// and hotfixes the patch version, and Other nothing. Sections without a bump
+// bump the patch version. Returns None if no section calls for a bump
+func (r *Release) Bump() Bump {
+	for _, pr := range r.PullRequests {
+		if r.isBreaking(pr) {
+			return Major
+		}
+	}
+	bump := None
+	for _, section := range r.GetPullRequestSections() {
+		switch section.Bump {
+		case Major:
+			return Major
+		case Minor:
+			bump = Minor
+		case Patch, "":
+			if bump == None {
+				bump = Patch
+			}
+		}
+	}
+	return bump
+}
+
+// SuggestVersion suggests the tag of the next release by bumping the version of
+// the base tag. The prefix of the base tag is kept, so 'v1.2.3' with features
+// suggests 'v1.3.0'. Returns ErrNothingToRelease if the release has no pull
+// requests calling for a bump, like a release with only Other pull requests
+func (r *Release) SuggestVersion() (*VersionSuggestion, error) {
+	bump := r.Bump()
+	if len(r.PullRequests) == 0 || bump == None {
+		return nil, ErrNothingToRelease
+	}
+	next, err := NextVersion(r.Base, bump)
+	if err != nil {
+		return nil, err
+	}
+	return &VersionSuggestion{Current: r.Base, Next: next, Bump: bump}, nil
+}
+
+// NextVersion bumps the version of a tag, keeping its prefix.
+// For instance tag 'v1.2.3' with a minor bump returns 'v1.3.0'
+func NextVersion(tagName string, bump Bump) (string, error) {
+	current, err := version.NewVersion(tagName)
+	if err != nil {
+		return "", fmt.Errorf("%s is not a semver tag: %v", tagName, err)
+	}
+	prefix := strings.Replace(tagName, current.String(), "", 1)
+	segments := current.Segments()
+	major, minor, patch := segments[0], segments[1], segments[2]
+
+	switch bump {
+	case Major:
+		major, minor, patch = major+1, 0, 0
+	case Minor:
+		minor, patch = minor+1, 0
+	case Patch:
+		patch++
+	}
+	return fmt.Sprintf("%s%d.%d.%d", prefix, major, minor, patch), nil
+}
@@ -0,0 +1,49 @@
+package release
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/franzwilhelm/gitflow-release-notes/githubutil"
+)
+
+const (
+	windowTitleFormat    = "2006-01-02 15:04 MST"
+	windowFilenameFormat = "2006-01-02T1504"
+)
+
+// TimeWindow is the merge time range of a release made from a time window.
+// Since is inclusive and Until exclusive
+type TimeWindow struct {
+	Since time.Time `json:"since" yaml:"since"`
+	Until time.Time `json:"until" yaml:"until"`
+}
+
+// String returns the time range formatted for release titles
+func (w *TimeWindow) String() string {
+	return fmt.Sprintf("%s to %s", w.Since.Format(windowTitleFormat), w.Until.Format(windowTitleFormat))
+}
+
+func (w *TimeWindow) filename() string {
+	return fmt.Sprintf("%s_%s", w.Since.Format(windowFilenameFormat), w.Until.Format(windowFilenameFormat))
+}
+
+func (w *TimeWindow) searchQuery(base string) string {
+	return fmt.Sprintf("is:pr base:%s merged:%s..%s", base, w.Since.Format(time.RFC3339), w.Until.Format(time.RFC3339))
+}
+
+// GenerateReleaseBetweenTimes generates a release containing all pull requests
+// merged into the base branch in the time window
+func GenerateReleaseBetweenTimes(base string, window TimeWindow) (*Release, error) {
+	prs, err := githubutil.GetPullRequestsMergedBetween(base, window.Since, window.Until)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch pull requests: %v", err)
+	}
+	release := &Release{Base: base, Window: &window, Repository: githubutil.Repo}
+	for _, pr := range prs {
+		if !IsExcluded(pr) {
+			release.PullRequests = append(release.PullRequests, pr)
+		}
+	}
+	return release, nil
+}
@@ -7,7 +7,6 @@ import (
 	"net/http"
 	"strings"
 
-	"github.com/franzwilhelm/gitflow-release-notes/gitflow"
 	"github.com/google/go-github/github"
 	slackify "github.com/karriereat/blackfriday-slack"
 )
@@ -16,7 +15,7 @@ var webhookURL string
 
 // WebhookMessage holds the message to send to slack
 type WebhookMessage struct {
-	Channel     string       `json:"channel"`
+	Channel     string       `json:"channel,omitempty"`
 	Username    string       `json:"username,omitempty"`
 	IconURL     string       `json:"icon_url,omitempty"`
 	Text        string       `json:"text,omitempty"`
@@ -37,14 +36,26 @@ type Attachment struct {
 	FooterIcon string   `json:"footer_icon,omitempty"`
 }
 
-// UsePullRequests formats the data from pull requests and adds them to the attachment
-func (a *Attachment) UsePullRequests(prs []github.PullRequest) {
-	a.Text += "──────\n"
+// Formatter formats the title and body shown for pull requests
+type Formatter interface {
+	DisplayTitle(pr github.PullRequest) string
+	DisplayBody(pr github.PullRequest) string
+}
+
+// UsePullRequests formats the data from pull requests and adds them to the attachment.
+// The pull requests are shown below the heading if it's not empty
+func (a *Attachment) UsePullRequests(heading string, prs []github.PullRequest, formatter Formatter) {
+	if a.Text == "" {
+		a.Text += "──────\n"
+	}
+	if heading != "" {
+		a.Text += fmt.Sprintf("*%s*\n", heading)
+	}
 	for _, pr := range prs {
-		title := fmt.Sprintf("<%s|#%v>: _*%s*_", pr.GetHTMLURL(), pr.GetNumber(), gitflow.RemovePrefixes(pr.GetTitle()))
+		title := fmt.Sprintf("<%s|#%v>: _*%s*_", pr.GetHTMLURL(), pr.GetNumber(), formatter.DisplayTitle(pr))
 		a.Text += fmt.Sprintf("%s\n", title)
-		if pr.GetBody() != "" {
-			body := string(slackify.Run([]byte(pr.GetBody())))
+		if body := formatter.DisplayBody(pr); body != "" {
+			body = string(slackify.Run([]byte(body)))
 			a.Text += fmt.Sprintf("%s\n", strings.TrimRight(body, "\n"))
 		}
 		a.Text += "\n"
@@ -52,6 +63,12 @@ func (a *Attachment) UsePullRequests(prs []github.PullRequest) {
 	a.MarkdownIn = []string{"text"}
 }
 
+// UseMarkdown converts markdown to slack formatting and adds it to the attachment
+func (a *Attachment) UseMarkdown(markdown string) {
+	a.Text += strings.TrimRight(string(slackify.Run([]byte(markdown))), "\n") + "\n"
+	a.MarkdownIn = []string{"text"}
+}
+
 // Initialize sets the webhook url of the slack request
 func Initialize(url string) {
 	webhookURL = url